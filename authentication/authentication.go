@@ -33,12 +33,7 @@ func AuthHandler() mux.MiddlewareFunc {
 				return
 			}
 
-			auth := Auth{
-				clientID: clientID,
-				userID:   userID,
-				userRole: userRole,
-			}
-			ctx = context.WithValue(ctx, authKey, auth)
+			ctx = ContextWithAuth(ctx, NewAuth(userID, userRole, clientID))
 			r = r.WithContext(ctx)
 			next.ServeHTTP(w, r)
 		}
@@ -53,6 +48,20 @@ type Auth struct {
 	userRole string
 }
 
+// NewAuth creates Auth, e.g. from the headers of a message
+func NewAuth(userID string, userRole string, clientID string) Auth {
+	return Auth{
+		clientID: clientID,
+		userID:   userID,
+		userRole: userRole,
+	}
+}
+
+// ContextWithAuth returns a copy of ctx that carries auth
+func ContextWithAuth(ctx context.Context, auth Auth) context.Context {
+	return context.WithValue(ctx, authKey, auth)
+}
+
 // GetAuthFromContext extracts and return Auth object from context
 func GetAuthFromContext(ctx context.Context) (Auth, bool) {
 	auth, ok := ctx.Value(authKey).(Auth)
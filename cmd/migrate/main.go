@@ -0,0 +1,102 @@
+// Command migrate applies the SQL migrations of a directory to a database.
+//
+//	migrate -dsn "user:pass@tcp(localhost:3306)/orders" -dir ./migrations up
+//	migrate -dsn ... -dir ./migrations down
+//	migrate -dsn ... -dir ./migrations to 20200101120000
+//	migrate -dsn ... -dir ./migrations status
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/dhyaniarun1993/foody-common/datastore/sql"
+	"github.com/dhyaniarun1993/foody-common/datastore/sql/migrate"
+	"github.com/dhyaniarun1993/foody-common/tracer"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DSN"), "data source name of the database")
+	driver := flag.String("driver", "mysql", "database driver")
+	dir := flag.String("dir", "migrations", "directory with the migration files")
+	collector := flag.String("collector", "", "jaeger collector endpoint, tracing is disabled if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] up|down|to <version>|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *dsn == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var t opentracing.Tracer = opentracing.NoopTracer{}
+	var closer io.Closer
+	if *collector != "" {
+		t, closer = tracer.InitJaeger(tracer.Configuration{
+			ServiceName: "migrate",
+			Sampler:     tracer.SamplerConfig{Type: "const", Param: 1},
+			Reporter:    tracer.ReporterConfig{CollectorEndpoint: *collector},
+		})
+	}
+
+	err := run(t, *dsn, *driver, *dir, flag.Args())
+	if closer != nil {
+		closer.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(t opentracing.Tracer, dsn string, driver string, dir string, args []string) error {
+	db := sql.CreatePool(sql.Configuration{DSN: dsn}, driver, t)
+	defer db.Close()
+
+	migrator, err := migrate.New(db, os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+
+	span := t.StartSpan("migrate " + args[0])
+	defer span.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			return fmt.Errorf("to needs a version")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %v", args[1], err)
+		}
+		return migrator.To(ctx, version)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	validatorv9 "gopkg.in/go-playground/validator.v9"
+	"gopkg.in/yaml.v2"
+
+	"github.com/dhyaniarun1993/foody-common/validator"
+)
+
+// Option configures Load
+type Option func(*loader)
+
+// WithPrefix prefixes the environment variables with prefix and an
+// underscore, like envconfig.Process
+func WithPrefix(prefix string) Option {
+	return func(loader *loader) {
+		loader.prefix = strings.ToUpper(prefix)
+	}
+}
+
+// WithFile reads the configuration from a YAML or JSON file, chosen by the
+// extension. Files and providers given later override the earlier ones. Keys
+// match the field names whatever their case and separators, e.g.
+// max_pool_size or maxPoolSize for MaxPoolSize.
+func WithFile(path string) Option {
+	return WithProvider(NewFileProvider(path))
+}
+
+// WithOptionalFile reads the file like WithFile if it exists
+func WithOptionalFile(path string) Option {
+	return func(loader *loader) {
+		if _, err := os.Stat(path); err == nil {
+			WithFile(path)(loader)
+		}
+	}
+}
+
+// WithProvider reads the configuration from provider, e.g. a remote
+// configuration service
+func WithProvider(provider Provider) Option {
+	return func(loader *loader) {
+		loader.providers = append(loader.providers, provider)
+	}
+}
+
+// WithLookupEnv replaces os.LookupEnv, e.g. in tests
+func WithLookupEnv(lookupEnv func(key string) (string, bool)) Option {
+	return func(loader *loader) {
+		loader.lookupEnv = lookupEnv
+	}
+}
+
+// Error lists every problem found while loading a configuration
+type Error struct {
+	Problems []string
+}
+
+func (err *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(err.Problems, "\n  ")
+}
+
+type loader struct {
+	prefix    string
+	providers []Provider
+	lookupEnv func(key string) (string, bool)
+}
+
+func newLoader(opts []Option) *loader {
+	loader := &loader{lookupEnv: os.LookupEnv}
+	for _, opt := range opts {
+		opt(loader)
+	}
+	return loader
+}
+
+// Load fills the struct pointed by cfg, typically made of the Configuration
+// structs of the packages in use. Every field is read from its environment
+// variable first, then from the files and providers and then from its default
+// tag. The envconfig tags are honoured: required, default, split_words,
+// envconfig and ignored. The result is then checked with validator.New(). All
+// the problems are reported at once in an *Error.
+func Load(cfg interface{}, opts ...Option) error {
+	loader := newLoader(opts)
+	fileValues, _, err := loader.fetch(context.Background())
+	if err != nil {
+		return err
+	}
+	return loader.load(cfg, fileValues)
+}
+
+// fetch reads the documents of the providers and returns their merged values
+// and a digest of their content
+func (loader *loader) fetch(ctx context.Context) (map[string]interface{}, string, error) {
+	values := map[string]interface{}{}
+	hash := sha256.New()
+	for _, provider := range loader.providers {
+		data, format, err := provider.Fetch(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("config: unable to read %s: %v", provider.Name(), err)
+		}
+		hash.Write(data)
+		document, err := decode(data, format, provider.Name())
+		if err != nil {
+			return nil, "", err
+		}
+		merge(values, document)
+	}
+	return values, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// load fills cfg from the environment, fileValues and the defaults and
+// validates it
+func (loader *loader) load(cfg interface{}, fileValues map[string]interface{}) error {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: %T is not a pointer to a struct", cfg)
+	}
+
+	var problems []string
+	knownKeys := map[string]bool{}
+	for _, field := range fields(value.Elem(), "", loader.prefix, "") {
+		knownKeys[field.fileKey] = true
+		if text, ok := loader.lookupEnv(field.envKey); ok {
+			if err := setString(field.value, text); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid value of %s: %v", field.path, field.envKey, err))
+			}
+			continue
+		}
+		if raw, ok := lookup(fileValues, field.fileKey); ok {
+			if err := setFileValue(field.value, raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid value in file: %v", field.path, err))
+			}
+			continue
+		}
+		if field.hasDefault && field.value.IsZero() {
+			if err := setString(field.value, field.defaultValue); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid default: %v", field.path, err))
+			}
+			continue
+		}
+		if field.required && field.value.IsZero() {
+			problems = append(problems, fmt.Sprintf("%s: missing, set %s", field.path, field.envKey))
+		}
+	}
+	for _, key := range unknownKeys(fileValues, "", "", knownKeys) {
+		problems = append(problems, fmt.Sprintf("%s: unknown key in file", key))
+	}
+
+	if err := validator.New().Struct(cfg); err != nil {
+		validationErrors, ok := err.(validatorv9.ValidationErrors)
+		if !ok {
+			return err
+		}
+		for _, fieldError := range validationErrors {
+			path := fieldError.Namespace()
+			path = path[strings.Index(path, ".")+1:]
+			problems = append(problems, fmt.Sprintf("%s: failed %q validation", path, fieldError.Tag()))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &Error{problems}
+	}
+	return nil
+}
+
+// decode decodes a YAML or JSON document into maps with string keys
+func decode(data []byte, format string, name string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	switch strings.TrimPrefix(strings.ToLower(format), ".") {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, fmt.Errorf("config: unable to decode %s: %v", name, err)
+		}
+	case FormatYAML, "yml":
+		var raw map[interface{}]interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("config: unable to decode %s: %v", name, err)
+		}
+		if raw != nil {
+			values = stringKeys(raw).(map[string]interface{})
+		}
+	default:
+		return nil, fmt.Errorf("config: unknown format %q of %s, use yaml or json", format, name)
+	}
+	return values, nil
+}
+
+// stringKeys converts the maps decoded by yaml to maps with string keys
+func stringKeys(raw interface{}) interface{} {
+	switch typed := raw.(type) {
+	case map[interface{}]interface{}:
+		values := make(map[string]interface{}, len(typed))
+		for key, value := range typed {
+			values[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return values
+	case []interface{}:
+		for i, value := range typed {
+			typed[i] = stringKeys(value)
+		}
+	}
+	return raw
+}
+
+// merge copies source into target, nested maps are merged
+func merge(target map[string]interface{}, source map[string]interface{}) {
+	for key, value := range source {
+		sourceMap, isMap := value.(map[string]interface{})
+		targetMap, targetIsMap := target[key].(map[string]interface{})
+		if isMap && targetIsMap {
+			merge(targetMap, sourceMap)
+			continue
+		}
+		target[key] = value
+	}
+}
+
+// lookup finds the value of the normalized dotted key in values
+func lookup(values map[string]interface{}, key string) (interface{}, bool) {
+	parts := strings.SplitN(key, ".", 2)
+	for name, value := range values {
+		if normalize(name) != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			return value, true
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			return lookup(nested, parts[1])
+		}
+	}
+	return nil, false
+}
+
+// unknownKeys returns the keys of values that match no field
+func unknownKeys(values map[string]interface{}, path string, prefix string, knownKeys map[string]bool) []string {
+	var keys []string
+	for name, value := range values {
+		key := joinPath(prefix, normalize(name), ".")
+		if knownKeys[key] {
+			continue
+		}
+		nested, isMap := value.(map[string]interface{})
+		if isMap && hasPrefix(knownKeys, key) {
+			keys = append(keys, unknownKeys(nested, joinPath(path, name, "."), key, knownKeys)...)
+			continue
+		}
+		keys = append(keys, joinPath(path, name, "."))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hasPrefix(keys map[string]bool, prefix string) bool {
+	for key := range keys {
+		if strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is a leaf of a configuration struct
+type field struct {
+	// path is the Go path of the field, e.g. Mongo.URI
+	path string
+	// envKey is the environment variable of the field, named like envconfig
+	// does, e.g. ORDER_MONGO_URI
+	envKey string
+	// fileKey is the normalized path of the field in files, e.g. mongo.uri
+	fileKey      string
+	defaultValue string
+	hasDefault   bool
+	required     bool
+	secret       bool
+	value        reflect.Value
+}
+
+var (
+	gatherRegexp  = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
+	acronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
+	secretRegexp  = regexp.MustCompile("(?i)(password|secret|token|dsn|apikey|credential|privatekey)")
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// fields returns the leaves of the struct value, nested structs are walked
+// with their key as prefix. Nil nested pointers are allocated when value is
+// settable and left untouched otherwise.
+func fields(value reflect.Value, path string, envPrefix string, filePrefix string) []field {
+	var result []field
+	structType := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		structField := structType.Field(i)
+		if structField.PkgPath != "" || structField.Tag.Get("ignored") == "true" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		fieldPath := joinPath(path, structField.Name, ".")
+		envKey := joinPath(envPrefix, envName(structField), "_")
+		fileKey := joinPath(filePrefix, normalize(structField.Name), ".")
+
+		if isNested(structField.Type) {
+			if structField.Type.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() && fieldValue.CanSet() {
+					fieldValue.Set(reflect.New(structField.Type.Elem()))
+				} else if fieldValue.IsNil() {
+					// read only walks, e.g. Redacted, see the zero value
+					fieldValue = reflect.New(structField.Type.Elem())
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if structField.Anonymous && structField.Tag.Get("envconfig") == "" {
+				result = append(result, fields(fieldValue, path, envPrefix, filePrefix)...)
+			} else {
+				result = append(result, fields(fieldValue, fieldPath, envKey, fileKey)...)
+			}
+			continue
+		}
+
+		defaultValue, hasDefault := structField.Tag.Lookup("default")
+		result = append(result, field{
+			path:         fieldPath,
+			envKey:       envKey,
+			fileKey:      fileKey,
+			defaultValue: defaultValue,
+			hasDefault:   hasDefault,
+			required:     structField.Tag.Get("required") == "true",
+			secret:       isSecret(structField),
+			value:        fieldValue,
+		})
+	}
+	return result
+}
+
+func isNested(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.Struct && fieldType != timeType &&
+		!reflect.PtrTo(fieldType).Implements(textUnmarshalerType)
+}
+
+func isSecret(structField reflect.StructField) bool {
+	if secret, ok := structField.Tag.Lookup("secret"); ok {
+		return secret == "true"
+	}
+	return secretRegexp.MatchString(structField.Name)
+}
+
+// envName is the key of the field the way envconfig names it
+func envName(structField reflect.StructField) string {
+	if name := structField.Tag.Get("envconfig"); name != "" {
+		return strings.ToUpper(name)
+	}
+	name := structField.Name
+	if structField.Tag.Get("split_words") == "true" {
+		var words []string
+		for _, match := range gatherRegexp.FindAllStringSubmatch(name, -1) {
+			if parts := acronymRegexp.FindStringSubmatch(match[0]); len(parts) == 3 {
+				words = append(words, parts[1], parts[2])
+			} else {
+				words = append(words, match[0])
+			}
+		}
+		name = strings.Join(words, "_")
+	}
+	return strings.ToUpper(name)
+}
+
+// normalize makes file keys match whatever their case and separators, so
+// maxPoolSize, max_pool_size and max-pool-size all match MaxPoolSize
+func normalize(key string) string {
+	key = strings.ToLower(key)
+	key = strings.Replace(key, "_", "", -1)
+	return strings.Replace(key, "-", "", -1)
+}
+
+func joinPath(prefix string, name string, separator string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + separator + name
+}
+
+// setString sets value from its text form: lists are comma separated and
+// maps are comma separated key:value pairs, like envconfig
+func setString(value reflect.Value, text string) error {
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() == durationType {
+			parsed, err := time.ParseDuration(text)
+			if err != nil {
+				return err
+			}
+			value.SetInt(int64(parsed))
+			return nil
+		}
+		parsed, err := strconv.ParseInt(text, 0, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(text, 0, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(text, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(parsed)
+	case reflect.Ptr:
+		pointer := reflect.New(value.Type().Elem())
+		if err := setString(pointer.Elem(), text); err != nil {
+			return err
+		}
+		value.Set(pointer)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), 0, 0)
+		if strings.TrimSpace(text) != "" {
+			for _, item := range strings.Split(text, ",") {
+				element := reflect.New(value.Type().Elem()).Elem()
+				if err := setString(element, strings.TrimSpace(item)); err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, element)
+			}
+		}
+		value.Set(slice)
+	case reflect.Map:
+		entries := reflect.MakeMap(value.Type())
+		if strings.TrimSpace(text) != "" {
+			for _, pair := range strings.Split(text, ",") {
+				parts := strings.SplitN(pair, ":", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("invalid map item %q", pair)
+				}
+				key := reflect.New(value.Type().Key()).Elem()
+				if err := setString(key, strings.TrimSpace(parts[0])); err != nil {
+					return err
+				}
+				element := reflect.New(value.Type().Elem()).Elem()
+				if err := setString(element, strings.TrimSpace(parts[1])); err != nil {
+					return err
+				}
+				entries.SetMapIndex(key, element)
+			}
+		}
+		value.Set(entries)
+	default:
+		return fmt.Errorf("unsupported type %v", value.Type())
+	}
+	return nil
+}
+
+// setFileValue sets value from a value decoded from a file
+func setFileValue(value reflect.Value, raw interface{}) error {
+	switch typed := raw.(type) {
+	case nil:
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	case []interface{}:
+		if value.Kind() != reflect.Slice {
+			return fmt.Errorf("a list is not a %v", value.Type())
+		}
+		slice := reflect.MakeSlice(value.Type(), 0, len(typed))
+		for _, item := range typed {
+			element := reflect.New(value.Type().Elem()).Elem()
+			if err := setFileValue(element, item); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, element)
+		}
+		value.Set(slice)
+		return nil
+	case map[string]interface{}:
+		if value.Kind() != reflect.Map {
+			return fmt.Errorf("an object is not a %v", value.Type())
+		}
+		entries := reflect.MakeMap(value.Type())
+		for name, item := range typed {
+			key := reflect.New(value.Type().Key()).Elem()
+			if err := setString(key, name); err != nil {
+				return err
+			}
+			element := reflect.New(value.Type().Elem()).Elem()
+			if err := setFileValue(element, item); err != nil {
+				return err
+			}
+			entries.SetMapIndex(key, element)
+		}
+		value.Set(entries)
+		return nil
+	}
+	return setString(value, fmt.Sprint(raw))
+}
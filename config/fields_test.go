@@ -0,0 +1,102 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDatabase struct {
+	URI         string
+	MaxPoolSize int `split_words:"true"`
+}
+
+// Shared is exported since unexported embedded structs are skipped like any
+// unexported field
+type Shared struct {
+	LogLevel string `split_words:"true"`
+}
+
+type testConfiguration struct {
+	Shared
+	Port           int
+	APIKey         string        `split_words:"true"`
+	ReplicaDSN     []string      `split_words:"true"`
+	RequestTimeout time.Duration `split_words:"true"`
+	Region         string        `envconfig:"aws_region"`
+	Mongo          testDatabase
+	Cache          *testDatabase
+	Named          Shared `envconfig:"shared"`
+	Skipped        string `ignored:"true"`
+	unexported     string
+}
+
+func TestFieldsKeys(t *testing.T) {
+	type keys struct {
+		path, envKey, fileKey string
+	}
+	want := []keys{
+		{"LogLevel", "APP_LOG_LEVEL", "loglevel"},
+		{"Port", "APP_PORT", "port"},
+		{"APIKey", "APP_API_KEY", "apikey"},
+		{"ReplicaDSN", "APP_REPLICA_DSN", "replicadsn"},
+		{"RequestTimeout", "APP_REQUEST_TIMEOUT", "requesttimeout"},
+		{"Region", "APP_AWS_REGION", "region"},
+		{"Mongo.URI", "APP_MONGO_URI", "mongo.uri"},
+		{"Mongo.MaxPoolSize", "APP_MONGO_MAX_POOL_SIZE", "mongo.maxpoolsize"},
+		{"Cache.URI", "APP_CACHE_URI", "cache.uri"},
+		{"Cache.MaxPoolSize", "APP_CACHE_MAX_POOL_SIZE", "cache.maxpoolsize"},
+		{"Named.LogLevel", "APP_SHARED_LOG_LEVEL", "named.loglevel"},
+	}
+
+	var cfg testConfiguration
+	var got []keys
+	for _, field := range fields(reflect.ValueOf(&cfg).Elem(), "", "APP", "") {
+		got = append(got, keys{field.path, field.envKey, field.fileKey})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields keys = %v, want %v", got, want)
+	}
+	if cfg.Cache == nil {
+		t.Error("nil nested pointer of a settable struct was not allocated")
+	}
+}
+
+func TestFieldsWithoutPrefix(t *testing.T) {
+	var cfg testDatabase
+	fields := fields(reflect.ValueOf(&cfg).Elem(), "", "", "")
+	if len(fields) != 2 || fields[1].envKey != "MAX_POOL_SIZE" || fields[1].fileKey != "maxpoolsize" {
+		t.Errorf("fields = %+v", fields)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, key := range []string{"MaxPoolSize", "maxPoolSize", "max_pool_size", "max-pool-size", "MAX_POOL_SIZE"} {
+		if got := normalize(key); got != "maxpoolsize" {
+			t.Errorf("normalize(%q) = %q, want maxpoolsize", key, got)
+		}
+	}
+}
+
+func TestLookupFileKey(t *testing.T) {
+	values := map[string]interface{}{
+		"mongo": map[string]interface{}{"max_pool_size": 10},
+		"Port":  8080,
+	}
+	tests := []struct {
+		key   string
+		want  interface{}
+		found bool
+	}{
+		{"mongo.maxpoolsize", 10, true},
+		{"port", 8080, true},
+		{"mongo.uri", nil, false},
+		{"port.value", nil, false},
+	}
+	for _, test := range tests {
+		got, found := lookup(values, test.key)
+		if found != test.found || got != test.want {
+			t.Errorf("lookup(%q) = %v, %v, want %v, %v", test.key, got, found, test.want, test.found)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// document formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// Provider provides a configuration document, e.g. from a file or a remote
+// configuration service. A Reloader fetches it again on every poll.
+type Provider interface {
+	// Name identifies the provider in errors
+	Name() string
+	// Fetch returns the document and its format, FormatYAML or FormatJSON
+	Fetch(ctx context.Context) ([]byte, string, error)
+}
+
+// FileProvider reads a YAML or JSON file, chosen by the extension. It stands
+// in for a remote provider in local setups.
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider creates a FileProvider for path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path}
+}
+
+// Name returns the path of the file
+func (provider *FileProvider) Name() string {
+	return provider.path
+}
+
+// Fetch reads the file
+func (provider *FileProvider) Fetch(ctx context.Context) ([]byte, string, error) {
+	data, err := ioutil.ReadFile(provider.path)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, filepath.Ext(provider.path), nil
+}
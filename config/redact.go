@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// redactedValue replaces the secrets
+const redactedValue = "******"
+
+// Redacted returns the effective configuration cfg as nested maps keyed by
+// field name with the secrets replaced. Fields tagged secret:"true" or named
+// like a password, secret, token, DSN, API key or credential are secrets,
+// secret:"false" opts out. Passwords in URLs such as the Mongo URI are
+// replaced as well. The result is empty if cfg is not a struct.
+func Redacted(cfg interface{}) map[string]interface{} {
+	value := reflect.ValueOf(cfg)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	redacted := map[string]interface{}{}
+	if value.Kind() != reflect.Struct {
+		return redacted
+	}
+
+	// walk a copy that is not addressable so that cfg is never modified
+	for _, field := range fields(reflect.ValueOf(value.Interface()), "", "", "") {
+		var fieldValue interface{}
+		switch {
+		case field.secret && !field.value.IsZero():
+			fieldValue = redactedValue
+			if field.value.Kind() == reflect.Slice {
+				items := make([]string, field.value.Len())
+				for i := range items {
+					items[i] = redactedValue
+				}
+				fieldValue = items
+			}
+		case field.value.Kind() == reflect.String:
+			fieldValue = redactURL(field.value.String())
+		default:
+			fieldValue = field.value.Interface()
+		}
+		setPath(redacted, strings.Split(field.path, "."), fieldValue)
+	}
+	return redacted
+}
+
+// String formats cfg as indented JSON with the secrets replaced, to log the
+// effective configuration at startup
+func String(cfg interface{}) string {
+	data, err := json.MarshalIndent(Redacted(cfg), "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// redactURL replaces the password of a URL, including the multi host URLs
+// of Mongo that net/url does not parse
+func redactURL(value string) string {
+	schemeEnd := strings.Index(value, "://")
+	if schemeEnd < 0 {
+		return value
+	}
+	rest := value[schemeEnd+3:]
+	authorityEnd := strings.IndexAny(rest, "/?#")
+	if authorityEnd < 0 {
+		authorityEnd = len(rest)
+	}
+	at := strings.LastIndex(rest[:authorityEnd], "@")
+	if at < 0 {
+		return value
+	}
+	colon := strings.Index(rest[:at], ":")
+	if colon < 0 {
+		return value
+	}
+	return value[:schemeEnd+3] + rest[:colon+1] + redactedValue + rest[at:]
+}
+
+func setPath(values map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := values[name].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			values[name] = nested
+		}
+		values = nested
+	}
+	values[path[len(path)-1]] = value
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/logger"
+)
+
+// ReloadOptions configures a Reloader, zero values use the defaults
+type ReloadOptions struct {
+	// Interval is how often the files and providers are polled, 10s by
+	// default
+	Interval time.Duration
+
+	// Logger logs the reloads and the rejected configurations when set
+	Logger *logger.Logger
+}
+
+type subscription[T any] struct {
+	name    string
+	changed func(previous T, next T) bool
+	apply   func(cfg T) error
+}
+
+// Reloader keeps a configuration of type T up to date with its files and
+// providers. A changed configuration is loaded and validated like Load does
+// and handed to the subscribers of the sections that changed. An invalid
+// configuration is rejected, and when a subscriber fails the subscribers
+// already updated get the previous configuration back.
+type Reloader[T any] struct {
+	loader  *loader
+	options ReloadOptions
+
+	// reloadMu serializes the reloads, mu guards current
+	reloadMu      sync.Mutex
+	digest        string
+	rejected      string
+	subscriptions []subscription[T]
+	mu            sync.Mutex
+	current       T
+}
+
+// NewReloader loads the configuration like Load
+func NewReloader[T any](options ReloadOptions, opts ...Option) (*Reloader[T], error) {
+	if options.Interval <= 0 {
+		options.Interval = 10 * time.Second
+	}
+	reloader := &Reloader[T]{loader: newLoader(opts), options: options}
+	if configType := reflect.TypeOf((*T)(nil)).Elem(); configType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: %v is not a struct", configType)
+	}
+
+	values, digest, err := reloader.loader.fetch(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err := reloader.loader.load(&reloader.current, values); err != nil {
+		return nil, err
+	}
+	reloader.digest = digest
+	return reloader, nil
+}
+
+// Current returns the configuration in use
+func (reloader *Reloader[T]) Current() T {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	return reloader.current
+}
+
+// Subscribe calls apply with the section of the configuration selected by
+// section whenever that section changes. apply returns an error to reject
+// the change, it is then called again with the previous section if a
+// subscriber before it had applied the change.
+func Subscribe[T any, S any](reloader *Reloader[T], name string, section func(cfg T) S, apply func(section S) error) {
+	reloader.reloadMu.Lock()
+	defer reloader.reloadMu.Unlock()
+	reloader.subscriptions = append(reloader.subscriptions, subscription[T]{
+		name: name,
+		changed: func(previous T, next T) bool {
+			return !reflect.DeepEqual(section(previous), section(next))
+		},
+		apply: func(cfg T) error {
+			return apply(section(cfg))
+		},
+	})
+}
+
+// Run polls the files and providers until ctx is cancelled and reloads the
+// configuration when they change
+func (reloader *Reloader[T]) Run(ctx context.Context) {
+	ticker := time.NewTicker(reloader.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := reloader.reload(ctx, false); err != nil {
+			reloader.logError(err, "Configuration rejected, keeping the current one")
+		}
+	}
+}
+
+// Reload loads the configuration now, even if the files and providers did
+// not change
+func (reloader *Reloader[T]) Reload(ctx context.Context) error {
+	return reloader.reload(ctx, true)
+}
+
+func (reloader *Reloader[T]) reload(ctx context.Context, force bool) error {
+	values, digest, err := reloader.loader.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	reloader.reloadMu.Lock()
+	defer reloader.reloadMu.Unlock()
+	if (digest == reloader.digest || digest == reloader.rejected) && !force {
+		return nil
+	}
+	var next T
+	if err := reloader.loader.load(&next, values); err != nil {
+		reloader.rejected = digest
+		return err
+	}
+	previous := reloader.Current()
+	if reflect.DeepEqual(previous, next) {
+		reloader.digest = digest
+		return nil
+	}
+
+	for i, subscription := range reloader.subscriptions {
+		if !subscription.changed(previous, next) {
+			continue
+		}
+		if err := subscription.apply(next); err != nil {
+			reloader.rollback(previous, next, i)
+			reloader.rejected = digest
+			return fmt.Errorf("config: %s rejected the configuration: %v", subscription.name, err)
+		}
+	}
+	reloader.mu.Lock()
+	reloader.current = next
+	reloader.mu.Unlock()
+	reloader.digest = digest
+	reloader.rejected = ""
+	reloader.logInfo("Configuration reloaded")
+	return nil
+}
+
+// rollback gives the previous configuration back to the subscribers before
+// the failed one that applied next
+func (reloader *Reloader[T]) rollback(previous T, next T, failed int) {
+	for i := failed - 1; i >= 0; i-- {
+		subscription := reloader.subscriptions[i]
+		if !subscription.changed(previous, next) {
+			continue
+		}
+		if err := subscription.apply(previous); err != nil {
+			reloader.logError(err, "Unable to roll back configuration", zap.String("subscriber", subscription.name))
+		}
+	}
+}
+
+func (reloader *Reloader[T]) logInfo(message string, fields ...zap.Field) {
+	if reloader.options.Logger != nil {
+		reloader.options.Logger.Info(message, fields...)
+	}
+}
+
+func (reloader *Reloader[T]) logError(err error, message string, fields ...zap.Field) {
+	if reloader.options.Logger != nil {
+		reloader.options.Logger.WithError(err).Error(message, fields...)
+	}
+}
@@ -0,0 +1,211 @@
+package changestream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/retry"
+)
+
+// Watcher opens a change stream, it is implemented by the Client, Database
+// and Collection wrappers of the mongo package
+type Watcher interface {
+	Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
+}
+
+// Namespace is the database and collection of the changed document
+type Namespace struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"coll"`
+}
+
+// UpdateDescription describes the fields changed by an update
+type UpdateDescription struct {
+	UpdatedFields bson.Raw `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// Event is a change stream event
+type Event struct {
+	ResumeToken       bson.Raw            `bson:"_id"`
+	OperationType     string              `bson:"operationType"`
+	FullDocument      bson.Raw            `bson:"fullDocument"`
+	DocumentKey       bson.Raw            `bson:"documentKey"`
+	Namespace         Namespace           `bson:"ns"`
+	UpdateDescription *UpdateDescription  `bson:"updateDescription"`
+	ClusterTime       primitive.Timestamp `bson:"clusterTime"`
+}
+
+// Handler handles a single event. The context carries the span of the event.
+type Handler func(ctx context.Context, event Event) errors.AppError
+
+// Option configures the optional behaviour of a Consumer
+type Option func(*Consumer)
+
+// WithPipeline filters and transforms the events with an aggregation pipeline
+func WithPipeline(pipeline interface{}) Option {
+	return func(consumer *Consumer) {
+		consumer.pipeline = pipeline
+	}
+}
+
+// WithFullDocument sets the fullDocument option of the change stream, use
+// options.UpdateLookup to receive the current document on updates
+func WithFullDocument(fullDocument options.FullDocument) Option {
+	return func(consumer *Consumer) {
+		consumer.fullDocument = fullDocument
+	}
+}
+
+// WithBackoff sets the backoff between reconnects, exponential from 100ms to
+// 30s by default
+func WithBackoff(backoff retry.Backoff) Option {
+	return func(consumer *Consumer) {
+		consumer.backoff = backoff
+	}
+}
+
+// Consumer handles the events of a change stream one at a time and persists
+// the resume token after every handled event, so that it restarts from the
+// last handled event after a crash
+type Consumer struct {
+	name         string
+	watcher      Watcher
+	store        TokenStore
+	tracer       opentracing.Tracer
+	handler      Handler
+	pipeline     interface{}
+	fullDocument options.FullDocument
+	backoff      retry.Backoff
+}
+
+// NewConsumer creates a Consumer. name identifies the resume token in store
+// and must be unique per consumer.
+func NewConsumer(name string, watcher Watcher, store TokenStore, tracer opentracing.Tracer,
+	handler Handler, opts ...Option) *Consumer {
+	consumer := &Consumer{
+		name:     name,
+		watcher:  watcher,
+		store:    store,
+		tracer:   tracer,
+		handler:  handler,
+		pipeline: mongo.Pipeline{},
+		backoff:  retry.ExponentialBackoff(100*time.Millisecond, 30*time.Second),
+	}
+	for _, opt := range opts {
+		opt(consumer)
+	}
+	return consumer
+}
+
+// Run consumes the change stream until ctx is cancelled or the handler fails.
+// The stream is reopened from the last saved token when it fails. Run
+// returns nil when ctx is cancelled and the handler error otherwise, the
+// token of the failed event is not saved so it is handled again on restart.
+func (consumer *Consumer) Run(ctx context.Context) errors.AppError {
+	var wait time.Duration
+	for attempt := 1; ; attempt++ {
+		handled, appErr, err := consumer.consume(ctx)
+		if appErr != nil {
+			return appErr
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if handled {
+			attempt, wait = 1, 0
+		}
+
+		wait = consumer.backoff.Next(attempt, wait)
+		if span := opentracing.SpanFromContext(ctx); span != nil && err != nil {
+			span.LogFields(
+				log.String("event", "changestream.reconnect"),
+				log.String("consumer", consumer.name),
+				log.String("message", err.Error()),
+				log.String("wait", wait.String()),
+			)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// consume opens the stream from the saved token and handles events until the
+// stream fails. It reports whether any event was handled, the handler or
+// token store error that stops the consumer, and the stream error.
+func (consumer *Consumer) consume(ctx context.Context) (bool, errors.AppError, error) {
+	token, err := consumer.store.Load(ctx, consumer.name)
+	if err != nil {
+		return false, nil, err
+	}
+	opts := options.ChangeStream()
+	if consumer.fullDocument != "" {
+		opts.SetFullDocument(consumer.fullDocument)
+	}
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+
+	stream, err := consumer.watcher.Watch(ctx, consumer.pipeline, opts)
+	if err != nil {
+		return false, nil, err
+	}
+	defer stream.Close(context.Background())
+
+	handled := false
+	for stream.Next(ctx) {
+		var event Event
+		if err := stream.Decode(&event); err != nil {
+			return handled, errors.NewAppError("Unable to decode change stream event", http.StatusInternalServerError, err), nil
+		}
+		if appErr := consumer.handle(ctx, event); appErr != nil {
+			return handled, appErr, nil
+		}
+		if err := consumer.store.Save(ctx, consumer.name, event.ResumeToken); err != nil {
+			return handled, nil, err
+		}
+		handled = true
+	}
+	return handled, nil, stream.Err()
+}
+
+// handle calls the handler with the event in its own span
+func (consumer *Consumer) handle(ctx context.Context, event Event) errors.AppError {
+	var spanOpts []opentracing.StartSpanOption
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		spanOpts = append(spanOpts, opentracing.ChildOf(parent.Context()))
+	}
+	span := consumer.tracer.StartSpan(fmt.Sprintf("ChangeStream.%v", consumer.name), spanOpts...)
+	defer span.Finish()
+	ext.Component.Set(span, "mongo.ChangeStream")
+	ext.SpanKind.Set(span, ext.SpanKindConsumerEnum)
+	span.SetTag("db.operation_type", event.OperationType)
+	span.SetTag("db.namespace", event.Namespace.Database+"."+event.Namespace.Collection)
+
+	err := consumer.handler(opentracing.ContextWithSpan(ctx, span), event)
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(
+			log.String("event", "error"),
+			log.Int("status", err.StatusCode()),
+			log.String("message", err.Error()),
+		)
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package changestream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	datastore "github.com/dhyaniarun1993/foody-common/datastore/mongo"
+)
+
+// TokenStore persists the resume token of a consumer so that it can restart
+// from the last handled event
+type TokenStore interface {
+	// Load returns the last saved token of the consumer or nil if there is none
+	Load(ctx context.Context, name string) (bson.Raw, error)
+	// Save saves the token of the consumer
+	Save(ctx context.Context, name string, token bson.Raw) error
+}
+
+type memoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]bson.Raw
+}
+
+// NewMemoryTokenStore creates a TokenStore that keeps the tokens in memory,
+// tokens are lost on restart
+func NewMemoryTokenStore() TokenStore {
+	return &memoryTokenStore{tokens: map[string]bson.Raw{}}
+}
+
+func (store *memoryTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.tokens[name], nil
+}
+
+func (store *memoryTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.tokens[name] = append(bson.Raw{}, token...)
+	return nil
+}
+
+type mongoTokenStore struct {
+	collection *datastore.Collection
+}
+
+type tokenDocument struct {
+	Name      string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// NewMongoTokenStore creates a TokenStore that keeps one document per
+// consumer in collection
+func NewMongoTokenStore(collection *datastore.Collection) TokenStore {
+	return &mongoTokenStore{collection}
+}
+
+func (store *mongoTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	var document tokenDocument
+	err := store.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return document.Token, nil
+}
+
+func (store *mongoTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	document := tokenDocument{Name: name, Token: token, UpdatedAt: time.Now()}
+	_, err := store.collection.ReplaceOne(ctx, bson.M{"_id": name}, document, options.Replace().SetUpsert(true))
+	return err
+}
+
+type redisTokenStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisTokenStore creates a TokenStore that keeps the token of every
+// consumer under prefix + name
+func NewRedisTokenStore(client *redis.Client, prefix string) TokenStore {
+	return &redisTokenStore{client, prefix}
+}
+
+func (store *redisTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	token, err := store.client.WithContext(ctx).Get(store.prefix + name).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bson.Raw(token), nil
+}
+
+func (store *redisTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	return store.client.WithContext(ctx).Set(store.prefix+name, []byte(token), 0).Err()
+}
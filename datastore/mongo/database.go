@@ -1,7 +1,8 @@
 package mongo
 
 import (
-	"github.com/opentracing/opentracing-go"
+	"context"
+
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -9,11 +10,20 @@ import (
 // Database is a wrapper on mongo.Database with tracing Capability
 type Database struct {
 	*mongo.Database
-	tracer opentracing.Tracer
+	instrument *instrumentation
 }
 
 // Collection gets a handle for a given collection in the database.
 func (db *Database) Collection(name string, opts ...*options.CollectionOptions) *Collection {
 	collection := db.Database.Collection(name, opts...)
-	return &Collection{collection, db.tracer}
+	return &Collection{collection, db.instrument}
+}
+
+// Watch is a tracing wrapper around mongo database Watch
+func (db *Database) Watch(ctx context.Context, pipeline interface{},
+	opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	ctx, call := db.instrument.startCall(ctx, db.Name(), "Watch", "pipeline", pipeline)
+	stream, err := db.Database.Watch(ctx, pipeline, opts...)
+	call.finish(ctx, nil, err)
+	return stream, err
 }
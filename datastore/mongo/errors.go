@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// duplicateKeyCodes are the server codes of duplicate key errors
+var duplicateKeyCodes = map[int]bool{11000: true, 11001: true, 12582: true}
+
+// networkErrorLabel is set by the driver on network failures
+const networkErrorLabel = "NetworkError"
+
+// hasErrorLabel checks if err or any error it wraps is a command error with
+// label
+func hasErrorLabel(err error, label string) bool {
+	var commandError mongo.CommandError
+	if !errors.As(err, &commandError) {
+		return false
+	}
+	for _, errorLabel := range commandError.Labels {
+		if errorLabel == label {
+			return true
+		}
+	}
+	return false
+}
+
+// isDuplicateKeyError checks if err is a write or command error for a
+// duplicate key
+func isDuplicateKeyError(err error) bool {
+	var writeException mongo.WriteException
+	if errors.As(err, &writeException) {
+		for _, writeError := range writeException.WriteErrors {
+			if duplicateKeyCodes[writeError.Code] {
+				return true
+			}
+		}
+	}
+	var bulkWriteException mongo.BulkWriteException
+	if errors.As(err, &bulkWriteException) {
+		for _, writeError := range bulkWriteException.WriteErrors {
+			if duplicateKeyCodes[writeError.Code] {
+				return true
+			}
+		}
+	}
+	var commandError mongo.CommandError
+	return errors.As(err, &commandError) && duplicateKeyCodes[int(commandError.Code)]
+}
+
+// isTransientError checks if err is a timeout or a network error
+func isTransientError(err error) bool {
+	var netError net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netError) ||
+		hasErrorLabel(err, networkErrorLabel)
+}
@@ -0,0 +1,86 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Filter builds a query filter, conditions are combined with AND
+type Filter struct {
+	conditions bson.D
+}
+
+// NewFilter creates an empty Filter that matches every document
+func NewFilter() *Filter {
+	return &Filter{conditions: bson.D{}}
+}
+
+// Eq matches documents where field equals value
+func (filter *Filter) Eq(field string, value interface{}) *Filter {
+	return filter.add(field, value)
+}
+
+// Ne matches documents where field does not equal value
+func (filter *Filter) Ne(field string, value interface{}) *Filter {
+	return filter.add(field, bson.M{"$ne": value})
+}
+
+// In matches documents where field equals any of values
+func (filter *Filter) In(field string, values ...interface{}) *Filter {
+	return filter.add(field, bson.M{"$in": values})
+}
+
+// Gt matches documents where field is greater than value
+func (filter *Filter) Gt(field string, value interface{}) *Filter {
+	return filter.add(field, bson.M{"$gt": value})
+}
+
+// Gte matches documents where field is greater than or equal to value
+func (filter *Filter) Gte(field string, value interface{}) *Filter {
+	return filter.add(field, bson.M{"$gte": value})
+}
+
+// Lt matches documents where field is less than value
+func (filter *Filter) Lt(field string, value interface{}) *Filter {
+	return filter.add(field, bson.M{"$lt": value})
+}
+
+// Lte matches documents where field is less than or equal to value
+func (filter *Filter) Lte(field string, value interface{}) *Filter {
+	return filter.add(field, bson.M{"$lte": value})
+}
+
+// Exists matches documents that have or do not have field
+func (filter *Filter) Exists(field string, exists bool) *Filter {
+	return filter.add(field, bson.M{"$exists": exists})
+}
+
+// Where adds a raw condition
+func (filter *Filter) Where(field string, condition interface{}) *Filter {
+	return filter.add(field, condition)
+}
+
+// Document returns the filter document. A nil Filter matches every document.
+// Conditions on the same field are combined with $and so that none of them is
+// lost.
+func (filter *Filter) Document() bson.D {
+	if filter == nil {
+		return bson.D{}
+	}
+	fields := map[string]bool{}
+	for _, condition := range filter.conditions {
+		if fields[condition.Key] {
+			and := make(bson.A, len(filter.conditions))
+			for i, condition := range filter.conditions {
+				and[i] = bson.D{condition}
+			}
+			return bson.D{{Key: "$and", Value: and}}
+		}
+		fields[condition.Key] = true
+	}
+	return append(bson.D{}, filter.conditions...)
+}
+
+func (filter *Filter) add(field string, condition interface{}) *Filter {
+	filter.conditions = append(filter.conditions, bson.E{Key: field, Value: condition})
+	return filter
+}
@@ -0,0 +1,367 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// index sync actions
+const (
+	IndexCreate    = "create"
+	IndexRecreate  = "recreate"
+	IndexDrop      = "drop"
+	IndexUnmanaged = "unmanaged"
+)
+
+// IndexSpec declares an index. Keys is ordered, e.g.
+// bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}, and uses
+// "text" as value for text indexes. PartialFilter should be a bson.D so that
+// it compares in a stable order. Name defaults to the name generated by
+// MongoDB.
+type IndexSpec struct {
+	Name          string
+	Keys          bson.D
+	Unique        bool
+	Sparse        bool
+	ExpireAfter   time.Duration
+	PartialFilter interface{}
+	Weights       bson.D
+}
+
+// IndexSyncOptions controls SyncIndexes
+type IndexSyncOptions struct {
+	// DryRun only reports the differences
+	DryRun bool
+	// DropUnmanaged drops the indexes that are not declared, _id is never dropped
+	DropUnmanaged bool
+}
+
+// IndexChange is a difference between the declared and the existing indexes
+type IndexChange struct {
+	Collection string
+	Name       string
+	Action     string
+	Reason     string
+}
+
+// IndexReport lists the differences found by SyncIndexes and whether they
+// were applied
+type IndexReport struct {
+	Changes []IndexChange
+	Applied bool
+}
+
+// existingIndex is an index as returned by listIndexes
+type existingIndex struct {
+	Name                    string   `bson:"name"`
+	Key                     bson.Raw `bson:"key"`
+	Unique                  bool     `bson:"unique"`
+	Sparse                  bool     `bson:"sparse"`
+	ExpireAfterSeconds      *int64   `bson:"expireAfterSeconds"`
+	PartialFilterExpression bson.Raw `bson:"partialFilterExpression"`
+	Weights                 bson.Raw `bson:"weights"`
+}
+
+// SyncIndexes makes the indexes of the collections in indexes match their
+// declaration. Missing indexes are created and indexes whose options changed
+// are dropped and created again. Collections that are not declared are not
+// touched. Every change is logged on the span.
+func (db *Database) SyncIndexes(ctx context.Context, indexes map[string][]IndexSpec,
+	opts IndexSyncOptions) (*IndexReport, error) {
+	ctx, call := db.instrument.startCall(ctx, db.Name(), "SyncIndexes")
+	call.setTag("db.dry_run", opts.DryRun)
+	report := &IndexReport{Applied: !opts.DryRun}
+
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		changes, models, err := db.diffIndexes(ctx, name, indexes[name], opts.DropUnmanaged)
+		if err != nil {
+			call.finish(ctx, nil, err)
+			return report, err
+		}
+		report.Changes = append(report.Changes, changes...)
+		for _, change := range changes {
+			call.logFields(
+				log.String("event", "index."+change.Action),
+				log.String("collection", change.Collection),
+				log.String("index", change.Name),
+				log.String("reason", change.Reason),
+			)
+		}
+		if opts.DryRun {
+			continue
+		}
+		if err := db.applyIndexes(ctx, name, changes, models); err != nil {
+			call.finish(ctx, nil, err)
+			return report, err
+		}
+	}
+	call.setTag("db.index_changes", len(report.Changes))
+	call.finish(ctx, nil, nil)
+	return report, nil
+}
+
+// diffIndexes compares the declared indexes of collection with the existing
+// ones and returns the changes and the models of the indexes to create
+func (db *Database) diffIndexes(ctx context.Context, collection string, specs []IndexSpec,
+	dropUnmanaged bool) ([]IndexChange, map[string]mongo.IndexModel, error) {
+	cursor, err := db.Database.Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var existing []existingIndex
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, nil, err
+	}
+	existingByName := map[string]existingIndex{}
+	for _, index := range existing {
+		existingByName[index.Name] = index
+	}
+
+	var changes []IndexChange
+	models := map[string]mongo.IndexModel{}
+	declared := map[string]bool{}
+	for _, spec := range specs {
+		name := spec.name()
+		declared[name] = true
+		current, ok := existingByName[name]
+		if !ok {
+			changes = append(changes, IndexChange{collection, name, IndexCreate, "missing"})
+			models[name] = spec.model()
+			continue
+		}
+		if reason := spec.diff(current); reason != "" {
+			changes = append(changes, IndexChange{collection, name, IndexRecreate, reason})
+			models[name] = spec.model()
+		}
+	}
+
+	for _, index := range existing {
+		if declared[index.Name] || index.Name == "_id_" {
+			continue
+		}
+		if dropUnmanaged {
+			changes = append(changes, IndexChange{collection, index.Name, IndexDrop, "not declared"})
+		} else {
+			changes = append(changes, IndexChange{collection, index.Name, IndexUnmanaged, "not declared, kept"})
+		}
+	}
+	return changes, models, nil
+}
+
+// applyIndexes drops the changed and unmanaged indexes and creates the
+// missing and changed ones
+func (db *Database) applyIndexes(ctx context.Context, collection string, changes []IndexChange,
+	models map[string]mongo.IndexModel) error {
+	indexes := db.Collection(collection).Indexes()
+	var create []mongo.IndexModel
+	for _, change := range changes {
+		switch change.Action {
+		case IndexDrop, IndexRecreate:
+			ctx, call := db.instrument.startCall(ctx, collection, "DropIndex", "name", change.Name)
+			_, err := indexes.DropOne(ctx, change.Name)
+			call.finish(ctx, nil, err)
+			if err != nil {
+				return err
+			}
+		}
+		if model, ok := models[change.Name]; ok {
+			create = append(create, model)
+		}
+	}
+	if len(create) == 0 {
+		return nil
+	}
+
+	ctx, call := db.instrument.startCall(ctx, collection, "CreateIndexes")
+	_, err := indexes.CreateMany(ctx, create)
+	call.finish(ctx, nil, err)
+	return err
+}
+
+// name returns the declared name or the name MongoDB generates from the keys
+func (spec IndexSpec) name() string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	parts := make([]string, 0, len(spec.Keys)*2)
+	for _, key := range spec.Keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+func (spec IndexSpec) isText() bool {
+	for _, key := range spec.Keys {
+		if key.Value == "text" {
+			return true
+		}
+	}
+	return false
+}
+
+func (spec IndexSpec) model() mongo.IndexModel {
+	indexOptions := options.Index().SetName(spec.name())
+	if spec.Unique {
+		indexOptions.SetUnique(true)
+	}
+	if spec.Sparse {
+		indexOptions.SetSparse(true)
+	}
+	if spec.ExpireAfter > 0 {
+		indexOptions.SetExpireAfterSeconds(int32(spec.ExpireAfter / time.Second))
+	}
+	if spec.PartialFilter != nil {
+		indexOptions.SetPartialFilterExpression(spec.PartialFilter)
+	}
+	if len(spec.Weights) > 0 {
+		indexOptions.SetWeights(spec.Weights)
+	}
+	return mongo.IndexModel{Keys: spec.Keys, Options: indexOptions}
+}
+
+// diff returns why current does not match spec, empty if it matches
+func (spec IndexSpec) diff(current existingIndex) string {
+	if spec.isText() {
+		if !sameDocument(spec.weights(), sortDocument(current.Weights)) {
+			return "text fields changed"
+		}
+	} else if !sameDocument(spec.Keys, current.Key) {
+		return "keys changed"
+	}
+	if spec.Unique != current.Unique {
+		return "unique changed"
+	}
+	if spec.Sparse != current.Sparse {
+		return "sparse changed"
+	}
+	var expireAfterSeconds int64
+	if current.ExpireAfterSeconds != nil {
+		expireAfterSeconds = *current.ExpireAfterSeconds
+	}
+	if int64(spec.ExpireAfter/time.Second) != expireAfterSeconds {
+		return "expireAfter changed"
+	}
+	if spec.PartialFilter == nil && current.PartialFilterExpression != nil ||
+		spec.PartialFilter != nil && !sameDocument(spec.PartialFilter, current.PartialFilterExpression) {
+		return "partial filter changed"
+	}
+	return ""
+}
+
+// weights returns the weights of the text fields as stored by MongoDB, 1
+// unless declared otherwise
+func (spec IndexSpec) weights() bson.D {
+	declared := map[string]interface{}{}
+	for _, weight := range spec.Weights {
+		declared[weight.Key] = weight.Value
+	}
+	var weights bson.D
+	for _, key := range spec.Keys {
+		if key.Value != "text" {
+			continue
+		}
+		weight, ok := declared[key.Key]
+		if !ok {
+			weight = 1
+		}
+		weights = append(weights, bson.E{Key: key.Key, Value: weight})
+	}
+	sort.Slice(weights, func(i, j int) bool { return weights[i].Key < weights[j].Key })
+	return weights
+}
+
+// sortDocument sorts the fields of document by name
+func sortDocument(document bson.Raw) bson.Raw {
+	var fields bson.D
+	if err := bson.Unmarshal(document, &fields); err != nil {
+		return document
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	sorted, err := bson.Marshal(fields)
+	if err != nil {
+		return document
+	}
+	return sorted
+}
+
+// sameDocument compares documents field by field ignoring the numeric types,
+// e.g. 1, int32(1) and 1.0 are the same key direction
+func sameDocument(declared interface{}, current bson.Raw) bool {
+	if current == nil {
+		return false
+	}
+	document, err := bson.Marshal(declared)
+	if err != nil {
+		return false
+	}
+	return sameElements(document, current)
+}
+
+// sameElements compares the fields of two documents or arrays, which must
+// have the same names in the same order
+func sameElements(declared bson.Raw, current bson.Raw) bool {
+	declaredElements, err := declared.Elements()
+	if err != nil {
+		return false
+	}
+	currentElements, err := current.Elements()
+	if err != nil || len(declaredElements) != len(currentElements) {
+		return false
+	}
+	for i, element := range declaredElements {
+		if element.Key() != currentElements[i].Key() ||
+			!sameValue(element.Value(), currentElements[i].Value()) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue compares numbers by value, documents and arrays by field and the
+// other values by type and content
+func sameValue(declared bson.RawValue, current bson.RawValue) bool {
+	declaredNumber, declaredIsNumber := number(declared)
+	currentNumber, currentIsNumber := number(current)
+	if declaredIsNumber || currentIsNumber {
+		return declaredIsNumber && currentIsNumber && declaredNumber == currentNumber
+	}
+	if declared.Type != current.Type {
+		return false
+	}
+	switch declared.Type {
+	case bsontype.EmbeddedDocument, bsontype.Array:
+		return sameElements(declared.Value, current.Value)
+	case bsontype.String:
+		return declared.StringValue() == current.StringValue()
+	}
+	return bytes.Equal(declared.Value, current.Value)
+}
+
+// number returns value as a float64 if it is a double, an int32 or an int64
+func number(value bson.RawValue) (float64, bool) {
+	switch value.Type {
+	case bsontype.Double:
+		return value.Double(), true
+	case bsontype.Int32:
+		return float64(value.Int32()), true
+	case bsontype.Int64:
+		return float64(value.Int64()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,240 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/datastore/sanitizer"
+	"github.com/dhyaniarun1993/foody-common/logger"
+)
+
+// Option configures the optional instrumentation of the Mongo wrappers
+type Option func(*instrumentation)
+
+// WithLogger logs the calls slower than the configured SlowQueryThreshold
+func WithLogger(logger *logger.Logger) Option {
+	return func(i *instrumentation) {
+		i.logger = logger
+	}
+}
+
+// WithIgnoredErrors sets the errors that do not mark the span as failed,
+// mongo.ErrNoDocuments by default
+func WithIgnoredErrors(errs ...error) Option {
+	return func(i *instrumentation) {
+		i.ignoredErrors = errs
+	}
+}
+
+// WithMetrics records per operation latency histograms in factory
+func WithMetrics(factory metrics.Factory) Option {
+	return func(i *instrumentation) {
+		i.metrics = factory.Namespace(metrics.NSOptions{Name: "mongo"})
+	}
+}
+
+// shapeSanitizer replaces every value, it is used to group slow calls
+var shapeSanitizer = sanitizer.New(sanitizer.Configuration{})
+
+// instrumentation carries the tracer, the document sanitizer and the optional
+// logger and metrics shared by Client, Database and Collection
+type instrumentation struct {
+	tracer             opentracing.Tracer
+	sanitizer          *sanitizer.Sanitizer
+	logger             *logger.Logger
+	slowQueryThreshold time.Duration
+	metrics            metrics.Factory
+	timers             sync.Map
+	ignoredErrors      []error
+}
+
+// call tracks a single instrumented call from start to finish
+type call struct {
+	*instrumentation
+	collection string
+	operation  string
+	args       []interface{}
+	span       opentracing.Span
+	start      time.Time
+}
+
+// startCall starts a child span if ctx has a span and returns the context to
+// pass down to the driver. args are label, value pairs that make the statement.
+func (i *instrumentation) startCall(ctx context.Context, collection string, operation string,
+	args ...interface{}) (context.Context, *call) {
+	c := &call{instrumentation: i, collection: collection, operation: operation, args: args, start: time.Now()}
+	span := opentracing.SpanFromContext(ctx)
+	if span != nil {
+		operationName := fmt.Sprintf("Mongo.%v.%v", collection, operation)
+		if collection == "" {
+			operationName = fmt.Sprintf("Mongo.%v", operation)
+		}
+		c.span = i.tracer.StartSpan(
+			operationName,
+			opentracing.ChildOf(span.Context()),
+		)
+		ext.Component.Set(c.span, "mongo.Client")
+		ext.SpanKind.Set(c.span, "client")
+		if len(args) > 0 {
+			ext.DBStatement.Set(c.span, i.sanitizer.Truncate(c.statement(i.sanitizer.BSON)))
+		}
+		ctx = opentracing.ContextWithSpan(ctx, c.span)
+	}
+	return ctx, c
+}
+
+// setTag sets a tag on the span of the call, if any
+func (c *call) setTag(key string, value interface{}) {
+	if c.span != nil {
+		c.span.SetTag(key, value)
+	}
+}
+
+// logFields logs fields on the span of the call, if any
+func (c *call) logFields(fields ...log.Field) {
+	if c.span != nil {
+		c.span.LogFields(fields...)
+	}
+}
+
+// statement formats the label, value pairs of the call with format
+func (c *call) statement(format func(interface{}) string) string {
+	parts := make([]string, 0, len(c.args)/2)
+	for i := 0; i+1 < len(c.args); i += 2 {
+		parts = append(parts, fmt.Sprintf("%v::%v", c.args[i], format(c.args[i+1])))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// finish records the latency, logs the call if it is slow, tags the result and
+// finishes the span. The error of a SingleResult is taken from the result.
+func (c *call) finish(ctx context.Context, result interface{}, err error) {
+	duration := time.Since(c.start)
+	tags, rowsAffected := resultTags(result)
+	if singleResult, ok := result.(*mongo.SingleResult); ok && singleResult != nil {
+		err = singleResult.Err()
+	}
+
+	if c.metrics != nil {
+		c.timer(c.collection, c.operation).Record(duration)
+	}
+	if c.logger != nil && c.slowQueryThreshold > 0 && duration >= c.slowQueryThreshold {
+		fields := []zap.Field{
+			zap.String("collection", c.collection),
+			zap.String("operation", c.operation),
+			zap.String("statement", c.sanitizer.Truncate(c.statement(shapeSanitizer.BSON))),
+			zap.Duration("duration", duration),
+		}
+		if rowsAffected >= 0 {
+			fields = append(fields, zap.Int64("rows-affected", rowsAffected))
+		}
+		c.logger.WithContext(ctx).Warn("Slow query", fields...)
+	}
+	if c.span != nil {
+		for key, value := range tags {
+			c.span.SetTag(key, value)
+		}
+		if err != nil && !c.isIgnored(err) {
+			ext.Error.Set(c.span, true)
+			c.span.LogFields(
+				log.String("event", "error"),
+				log.String("error.kind", fmt.Sprintf("%T", err)),
+				log.String("message", err.Error()),
+			)
+		}
+		c.span.Finish()
+	}
+}
+
+func (i *instrumentation) isIgnored(err error) bool {
+	for _, ignored := range i.ignoredErrors {
+		if errors.Is(err, ignored) {
+			return true
+		}
+	}
+	return false
+}
+
+// resultTags returns the span tags describing result and the number of
+// documents it affected, -1 when it is not known
+func resultTags(result interface{}) (map[string]interface{}, int64) {
+	switch result := result.(type) {
+	case *mongo.BulkWriteResult:
+		if result == nil {
+			return nil, -1
+		}
+		return map[string]interface{}{
+			"db.inserted_count": result.InsertedCount,
+			"db.matched_count":  result.MatchedCount,
+			"db.modified_count": result.ModifiedCount,
+			"db.deleted_count":  result.DeletedCount,
+			"db.upserted_count": result.UpsertedCount,
+		}, result.InsertedCount + result.ModifiedCount + result.DeletedCount + result.UpsertedCount
+	case *mongo.InsertOneResult:
+		if result == nil {
+			return nil, -1
+		}
+		return map[string]interface{}{"db.inserted_id": fmt.Sprint(result.InsertedID)}, 1
+	case *mongo.InsertManyResult:
+		if result == nil {
+			return nil, -1
+		}
+		return map[string]interface{}{
+			"db.inserted_count": len(result.InsertedIDs),
+			"db.inserted_ids":   fmt.Sprint(result.InsertedIDs),
+		}, int64(len(result.InsertedIDs))
+	case *mongo.DeleteResult:
+		if result == nil {
+			return nil, -1
+		}
+		return map[string]interface{}{"db.deleted_count": result.DeletedCount}, result.DeletedCount
+	case *mongo.UpdateResult:
+		if result == nil {
+			return nil, -1
+		}
+		tags := map[string]interface{}{
+			"db.matched_count":  result.MatchedCount,
+			"db.modified_count": result.ModifiedCount,
+			"db.upserted_count": result.UpsertedCount,
+		}
+		if result.UpsertedID != nil {
+			tags["db.upserted_id"] = fmt.Sprint(result.UpsertedID)
+		}
+		return tags, result.ModifiedCount + result.UpsertedCount
+	case *mongo.Cursor:
+		if result == nil {
+			return nil, -1
+		}
+		return map[string]interface{}{"db.cursor_id": result.ID()}, -1
+	case []interface{}:
+		return map[string]interface{}{"db.count": len(result)}, -1
+	case int64:
+		return map[string]interface{}{"db.count": result}, -1
+	default:
+		return nil, -1
+	}
+}
+
+func (i *instrumentation) timer(collection string, operation string) metrics.Timer {
+	key := collection + "." + operation
+	if timer, ok := i.timers.Load(key); ok {
+		return timer.(metrics.Timer)
+	}
+	timer, _ := i.timers.LoadOrStore(key, i.metrics.Timer(metrics.TimerOptions{
+		Name: "latency",
+		Tags: map[string]string{"collection": collection, "operation": operation},
+		Help: "Latency of Mongo calls",
+	}))
+	return timer.(metrics.Timer)
+}
@@ -2,48 +2,259 @@ package mongo
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+
+	"github.com/dhyaniarun1993/foody-common/datastore/sanitizer"
 )
 
-// Configuration provides configuration for MongoDB driver
+// Configuration provides configuration for MongoDB driver. Options that are
+// not set keep the value from the URI or the driver default.
 type Configuration struct {
 	URI      string `required:"true" split_words:"true"`
 	Database string `required:"true"`
+	AppName  string `split_words:"true"`
+
+	// Connection pool
+	MinPoolSize     uint64 `split_words:"true"`
+	MaxPoolSize     uint64 `split_words:"true"`
+	MaxConnIdleTime string `split_words:"true"`
+
+	// Timeouts, Close waits up to DisconnectTimeout for in use connections
+	ConnectTimeout         string `split_words:"true" default:"10s"`
+	ServerSelectionTimeout string `split_words:"true"`
+	PingTimeout            string `split_words:"true" default:"2s"`
+	DisconnectTimeout      string `split_words:"true" default:"10s"`
+
+	// Read preference mode(primary, primaryPreferred, secondary,
+	// secondaryPreferred or nearest), tags as dc:east,rack:1 and max staleness
+	ReadPreference             string            `split_words:"true"`
+	ReadPreferenceTags         map[string]string `split_words:"true"`
+	ReadPreferenceMaxStaleness string            `split_words:"true"`
+
+	// Read concern level(local, available, majority, linearizable or snapshot)
+	ReadConcern string `split_words:"true"`
+	// Write concern w as majority or number of nodes, and journal
+	WriteConcern        string `split_words:"true"`
+	WriteConcernJournal bool   `split_words:"true"`
+
+	// RetryableWrites overrides retryWrites of the URI when set
+	RetryableWrites *bool    `split_words:"true"`
+	Compressors     []string `split_words:"true"`
+
+	// Calls slower than the threshold are logged when a logger is provided
+	SlowQueryThreshold string `split_words:"true"`
+
+	// Sanitizer configures how documents are redacted on spans and logs
+	Sanitizer sanitizer.Configuration
 }
 
 // Client is a wrapper on mongo.Client with tracing Capability
 type Client struct {
 	*mongo.Client
-	tracer opentracing.Tracer
+	instrument        *instrumentation
+	disconnectTimeout time.Duration
 }
 
-// CreateMongoDBPool creates connection pool for MongoDB server
-func CreateMongoDBPool(configuration Configuration, tracer opentracing.Tracer) *Client {
-	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+// CreateMongoDBPool creates connection pool for MongoDB server and checks
+// that the server is reachable. Every command sent to the server is traced
+// and measured by a command monitor.
+func CreateMongoDBPool(configuration Configuration, tracer opentracing.Tracer, opts ...Option) (*Client, error) {
+	instrument := &instrumentation{
+		tracer:        tracer,
+		sanitizer:     sanitizer.New(configuration.Sanitizer),
+		ignoredErrors: []error{mongo.ErrNoDocuments},
+	}
+	var err error
+	if instrument.slowQueryThreshold, err = parseDuration("SlowQueryThreshold", configuration.SlowQueryThreshold); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(instrument)
+	}
+
+	clientOptions, err := clientOptions(configuration)
+	if err != nil {
+		return nil, err
+	}
+	clientOptions.SetMonitor(newCommandMonitor(instrument))
+	connectTimeout, err := parseDuration("ConnectTimeout", configuration.ConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	pingTimeout, err := parseDuration("PingTimeout", configuration.PingTimeout)
+	if err != nil {
+		return nil, err
+	}
+	disconnectTimeout, err := parseDuration("DisconnectTimeout", configuration.DisconnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), durationOr(connectTimeout, 10*time.Second))
 	defer connectCancel()
-	clientOptions := options.Client().ApplyURI(configuration.URI)
-	client, connectError := mongo.Connect(connectCtx, clientOptions)
-	if connectError != nil {
-		panic(connectError)
+	client, err := mongo.Connect(connectCtx, clientOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	pingCtx, pingCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	pingCtx, pingCancel := context.WithTimeout(context.Background(), durationOr(pingTimeout, 2*time.Second))
 	defer pingCancel()
-	pingError := client.Ping(pingCtx, readpref.Primary())
-	if pingError != nil {
-		panic(pingError)
+	if err := client.Ping(pingCtx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
 	}
 
-	return &Client{client, tracer}
+	return &Client{client, instrument, durationOr(disconnectTimeout, 10*time.Second)}, nil
+}
+
+// Disconnect is a tracing wrapper around mongo client Disconnect. It waits for
+// the in use connections to be returned to the pool until ctx is done.
+func (client *Client) Disconnect(ctx context.Context) error {
+	ctx, call := client.instrument.startCall(ctx, "", "Disconnect")
+	err := client.Client.Disconnect(ctx)
+	call.finish(ctx, nil, err)
+	return err
+}
+
+// Close disconnects the client waiting at most DisconnectTimeout, it makes
+// Client an io.Closer for graceful shutdown
+func (client *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), client.disconnectTimeout)
+	defer cancel()
+	return client.Disconnect(ctx)
+}
+
+// clientOptions applies configuration on top of the options from the URI
+func clientOptions(configuration Configuration) (*options.ClientOptions, error) {
+	if _, err := connstring.Parse(configuration.URI); err != nil {
+		return nil, err
+	}
+	clientOptions := options.Client().ApplyURI(configuration.URI)
+	if configuration.AppName != "" {
+		clientOptions.SetAppName(configuration.AppName)
+	}
+	if configuration.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(configuration.MinPoolSize)
+	}
+	if configuration.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(configuration.MaxPoolSize)
+	}
+	if len(configuration.Compressors) > 0 {
+		clientOptions.SetCompressors(configuration.Compressors)
+	}
+	if configuration.RetryableWrites != nil {
+		clientOptions.SetRetryWrites(*configuration.RetryableWrites)
+	}
+
+	maxConnIdleTime, err := parseDuration("MaxConnIdleTime", configuration.MaxConnIdleTime)
+	if err != nil {
+		return nil, err
+	}
+	if maxConnIdleTime > 0 {
+		clientOptions.SetMaxConnIdleTime(maxConnIdleTime)
+	}
+	serverSelectionTimeout, err := parseDuration("ServerSelectionTimeout", configuration.ServerSelectionTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if serverSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(serverSelectionTimeout)
+	}
+
+	if configuration.ReadPreference != "" {
+		readPreference, err := readPreference(configuration)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetReadPreference(readPreference)
+	}
+	if configuration.ReadConcern != "" {
+		clientOptions.SetReadConcern(readconcern.New(readconcern.Level(configuration.ReadConcern)))
+	}
+	if configuration.WriteConcern != "" {
+		clientOptions.SetWriteConcern(writeConcern(configuration))
+	}
+	return clientOptions, nil
+}
+
+func writeConcern(configuration Configuration) *writeconcern.WriteConcern {
+	var w writeconcern.Option
+	if nodes, err := strconv.Atoi(configuration.WriteConcern); err == nil {
+		w = writeconcern.W(nodes)
+	} else if configuration.WriteConcern == "majority" {
+		w = writeconcern.WMajority()
+	} else {
+		w = writeconcern.WTagSet(configuration.WriteConcern)
+	}
+	if configuration.WriteConcernJournal {
+		return writeconcern.New(w, writeconcern.J(true))
+	}
+	return writeconcern.New(w)
+}
+
+func readPreference(configuration Configuration) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(configuration.ReadPreference)
+	if err != nil {
+		return nil, err
+	}
+	var readPreferenceOptions []readpref.Option
+	if len(configuration.ReadPreferenceTags) > 0 {
+		tags := make([]string, 0, len(configuration.ReadPreferenceTags)*2)
+		for name, value := range configuration.ReadPreferenceTags {
+			tags = append(tags, name, value)
+		}
+		readPreferenceOptions = append(readPreferenceOptions, readpref.WithTags(tags...))
+	}
+	maxStaleness, err := parseDuration("ReadPreferenceMaxStaleness", configuration.ReadPreferenceMaxStaleness)
+	if err != nil {
+		return nil, err
+	}
+	if maxStaleness > 0 {
+		readPreferenceOptions = append(readPreferenceOptions, readpref.WithMaxStaleness(maxStaleness))
+	}
+	return readpref.New(mode, readPreferenceOptions...)
+}
+
+// parseDuration parses value, an empty value is 0
+func parseDuration(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v: %w", name, err)
+	}
+	return duration, nil
+}
+
+func durationOr(duration time.Duration, fallback time.Duration) time.Duration {
+	if duration > 0 {
+		return duration
+	}
+	return fallback
 }
 
 // Database returns a handle for a given database.
 func (client *Client) Database(name string, opts ...*options.DatabaseOptions) *Database {
 	database := client.Client.Database(name, opts...)
-	return &Database{database, client.tracer}
+	return &Database{database, client.instrument}
+}
+
+// Watch is a tracing wrapper around mongo client Watch
+func (client *Client) Watch(ctx context.Context, pipeline interface{},
+	opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	ctx, call := client.instrument.startCall(ctx, "", "Watch", "pipeline", pipeline)
+	stream, err := client.Client.Watch(ctx, pipeline, opts...)
+	call.finish(ctx, nil, err)
+	return stream, err
 }
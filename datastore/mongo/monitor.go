@@ -0,0 +1,137 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// commandKey identifies a command between its started and finished events
+type commandKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// commandMonitor turns every command sent by the driver into a span and a
+// latency metric, including the commands that do not go through the wrappers
+// such as cursor getMore and RunCommand
+type commandMonitor struct {
+	instrument *instrumentation
+	spans      sync.Map
+	timers     sync.Map
+}
+
+func newCommandMonitor(instrument *instrumentation) *event.CommandMonitor {
+	monitor := &commandMonitor{instrument: instrument}
+	return &event.CommandMonitor{
+		Started:   monitor.started,
+		Succeeded: monitor.succeeded,
+		Failed:    monitor.failed,
+	}
+}
+
+// started starts a command span if ctx has a span
+func (monitor *commandMonitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	parent := opentracing.SpanFromContext(ctx)
+	if parent == nil {
+		return
+	}
+	span := monitor.instrument.tracer.StartSpan(
+		"Mongo.command."+evt.CommandName,
+		opentracing.ChildOf(parent.Context()),
+	)
+	ext.Component.Set(span, "mongo.Driver")
+	ext.SpanKind.Set(span, "client")
+	ext.DBType.Set(span, "mongo")
+	ext.DBInstance.Set(span, evt.DatabaseName)
+	span.SetTag("db.connection_id", evt.ConnectionID)
+	span.SetTag("db.request_id", evt.RequestID)
+	if collection := commandCollection(evt.Command); collection != "" {
+		span.SetTag("db.collection", collection)
+	}
+	if len(evt.Command) > 0 {
+		ext.DBStatement.Set(span, monitor.instrument.sanitizer.BSON(commandStatement(evt.Command)))
+	}
+	monitor.spans.Store(commandKey{evt.ConnectionID, evt.RequestID}, span)
+}
+
+func (monitor *commandMonitor) succeeded(ctx context.Context, evt *event.CommandSucceededEvent) {
+	monitor.record(evt.CommandName, "success", time.Duration(evt.DurationNanos))
+	if span := monitor.span(evt.CommandFinishedEvent); span != nil {
+		span.Finish()
+	}
+}
+
+func (monitor *commandMonitor) failed(ctx context.Context, evt *event.CommandFailedEvent) {
+	monitor.record(evt.CommandName, "failure", time.Duration(evt.DurationNanos))
+	if span := monitor.span(evt.CommandFinishedEvent); span != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(
+			log.String("event", "error"),
+			log.String("message", evt.Failure),
+		)
+		span.Finish()
+	}
+}
+
+// span removes and returns the span of the finished command, if any
+func (monitor *commandMonitor) span(evt event.CommandFinishedEvent) opentracing.Span {
+	span, ok := monitor.spans.LoadAndDelete(commandKey{evt.ConnectionID, evt.RequestID})
+	if !ok {
+		return nil
+	}
+	return span.(opentracing.Span)
+}
+
+func (monitor *commandMonitor) record(command string, status string, duration time.Duration) {
+	if monitor.instrument.metrics == nil {
+		return
+	}
+	key := command + "." + status
+	timer, ok := monitor.timers.Load(key)
+	if !ok {
+		timer, _ = monitor.timers.LoadOrStore(key, monitor.instrument.metrics.Timer(metrics.TimerOptions{
+			Name: "command_latency",
+			Tags: map[string]string{"command": command, "status": status},
+			Help: "Latency of commands sent to MongoDB",
+		}))
+	}
+	timer.(metrics.Timer).Record(duration)
+}
+
+// commandCollection returns the collection of commands such as find and
+// insert, i.e. the string value of the first element
+func commandCollection(command bson.Raw) string {
+	element, err := command.IndexErr(0)
+	if err != nil {
+		return ""
+	}
+	collection, _ := element.Value().StringValueOK()
+	return collection
+}
+
+// commandStatement drops the session, cluster time and other driver fields
+// from command
+func commandStatement(command bson.Raw) bson.D {
+	elements, err := command.Elements()
+	if err != nil {
+		return nil
+	}
+	statement := make(bson.D, 0, len(elements))
+	for _, element := range elements {
+		key := element.Key()
+		if strings.HasPrefix(key, "$") || key == "lsid" || key == "txnNumber" {
+			continue
+		}
+		statement = append(statement, bson.E{Key: key, Value: element.Value()})
+	}
+	return statement
+}
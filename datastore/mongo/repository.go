@@ -0,0 +1,348 @@
+package mongo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+)
+
+// document fields maintained by Repository
+const (
+	CreatedAtField = "createdAt"
+	UpdatedAtField = "updatedAt"
+	DeletedAtField = "deletedAt"
+)
+
+// defaultPageLimit is used when Page.Limit is not set
+const defaultPageLimit = 20
+
+// RepositoryOption configures the optional behaviour of a Repository
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	softDelete bool
+}
+
+// WithSoftDelete makes Delete set deletedAt instead of removing the document
+// and hides the deleted documents from every read
+func WithSoftDelete() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.softDelete = true
+	}
+}
+
+// Page selects a page of FindMany. After is the NextCursor of the previous
+// page, empty for the first page.
+type Page struct {
+	After string
+	Limit int64
+}
+
+// PageResult is a page of documents, NextCursor is empty on the last page
+type PageResult[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// Repository provides typed CRUD over a Collection. createdAt and updatedAt
+// are maintained on every write, not found errors are returned as 404 and
+// duplicate key errors as 409 AppError.
+type Repository[T any] struct {
+	collection *Collection
+	softDelete bool
+}
+
+// NewRepository creates a Repository of T over collection
+func NewRepository[T any](collection *Collection, opts ...RepositoryOption) *Repository[T] {
+	o := &repositoryOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Repository[T]{collection: collection, softDelete: o.softDelete}
+}
+
+// Collection returns the underlying Collection
+func (repository *Repository[T]) Collection() *Collection {
+	return repository.collection
+}
+
+// FindByID returns the document with id
+func (repository *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, errors.AppError) {
+	var document T
+	err := repository.collection.FindOne(ctx, repository.scope(NewFilter().Eq("_id", id))).Decode(&document)
+	if err != nil {
+		return nil, repository.error("find", err)
+	}
+	return &document, nil
+}
+
+// FindMany returns a page of the documents matching filter ordered by _id
+func (repository *Repository[T]) FindMany(ctx context.Context, filter *Filter, page Page) (*PageResult[T], errors.AppError) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	query := NewFilter().Where("$and", bson.A{filter.Document()})
+	if page.After != "" {
+		after, err := decodeCursor(page.After)
+		if err != nil {
+			return nil, errors.NewAppError("Invalid page cursor", http.StatusBadRequest, err)
+		}
+		query.Gt("_id", after)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit + 1)
+	cursor, err := repository.collection.Find(ctx, repository.scope(query), opts)
+	if err != nil {
+		return nil, repository.error("find", err)
+	}
+	defer cursor.Close(context.Background())
+
+	result := &PageResult[T]{Items: []T{}}
+	var lastID bson.RawValue
+	for cursor.Next(ctx) {
+		if int64(len(result.Items)) == limit {
+			result.NextCursor = encodeCursor(lastID)
+			break
+		}
+		var document T
+		if err := cursor.Decode(&document); err != nil {
+			return nil, repository.error("decode", err)
+		}
+		result.Items = append(result.Items, document)
+		lastID = cursor.Current.Lookup("_id")
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, repository.error("find", err)
+	}
+	return result, nil
+}
+
+// Insert inserts document with createdAt and updatedAt set to now. An
+// ObjectID is generated when the document has no _id. document is updated
+// with the generated _id and the timestamps.
+func (repository *Repository[T]) Insert(ctx context.Context, document *T) errors.AppError {
+	fields, err := toDocument(document)
+	if err != nil {
+		return repository.error("encode", err)
+	}
+	now := currentDate()
+	fields = setField(fields, CreatedAtField, now)
+	fields = setField(fields, UpdatedAtField, now)
+	if id, ok := lookupField(fields, "_id"); !ok || isZeroID(id) {
+		fields = setField(fields, "_id", primitive.NewObjectID())
+	}
+
+	if _, err := repository.collection.InsertOne(ctx, fields); err != nil {
+		return repository.error("insert", err)
+	}
+	if err := fromDocument(fields, document); err != nil {
+		return repository.error("decode", err)
+	}
+	return nil
+}
+
+// Update sets the fields of update, a struct or a map, on the document with
+// id and bumps updatedAt
+func (repository *Repository[T]) Update(ctx context.Context, id interface{}, update interface{}) errors.AppError {
+	fields, err := toDocument(update)
+	if err != nil {
+		return repository.error("encode", err)
+	}
+	fields = removeField(fields, "_id")
+	fields = removeField(fields, CreatedAtField)
+	fields = setField(fields, UpdatedAtField, currentDate())
+
+	result, err := repository.collection.UpdateOne(ctx, repository.scope(NewFilter().Eq("_id", id)),
+		bson.D{{Key: "$set", Value: fields}})
+	if err != nil {
+		return repository.error("update", err)
+	}
+	if result.MatchedCount == 0 {
+		return repository.error("update", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+// Upsert replaces the fields of the document matching filter with the fields
+// of document or inserts it. createdAt is only set on insert.
+func (repository *Repository[T]) Upsert(ctx context.Context, filter *Filter, document *T) errors.AppError {
+	fields, err := toDocument(document)
+	if err != nil {
+		return repository.error("encode", err)
+	}
+	now := currentDate()
+	onInsert := bson.D{{Key: CreatedAtField, Value: now}}
+	if id, ok := lookupField(fields, "_id"); ok && !isZeroID(id) {
+		onInsert = append(onInsert, bson.E{Key: "_id", Value: id})
+	}
+	fields = removeField(fields, "_id")
+	fields = removeField(fields, CreatedAtField)
+	fields = setField(fields, UpdatedAtField, now)
+
+	update := bson.D{{Key: "$set", Value: fields}, {Key: "$setOnInsert", Value: onInsert}}
+	_, err = repository.collection.UpdateOne(ctx, repository.scope(filter), update, options.Update().SetUpsert(true))
+	if err != nil {
+		return repository.error("upsert", err)
+	}
+	return nil
+}
+
+// Delete deletes the document with id. With soft delete the document is
+// only marked with deletedAt.
+func (repository *Repository[T]) Delete(ctx context.Context, id interface{}) errors.AppError {
+	filter := repository.scope(NewFilter().Eq("_id", id))
+	if repository.softDelete {
+		now := currentDate()
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: DeletedAtField, Value: now}, {Key: UpdatedAtField, Value: now}}}}
+		result, err := repository.collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return repository.error("delete", err)
+		}
+		if result.MatchedCount == 0 {
+			return repository.error("delete", mongo.ErrNoDocuments)
+		}
+		return nil
+	}
+
+	result, err := repository.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return repository.error("delete", err)
+	}
+	if result.DeletedCount == 0 {
+		return repository.error("delete", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+// scope adds the soft delete condition to filter
+func (repository *Repository[T]) scope(filter *Filter) bson.D {
+	if !repository.softDelete {
+		return filter.Document()
+	}
+	return NewFilter().Where("$and", bson.A{filter.Document()}).Eq(DeletedAtField, nil).Document()
+}
+
+// error maps err to an AppError, see appError
+func (repository *Repository[T]) error(operation string, err error) errors.AppError {
+	return appError(repository.collection.Name(), operation, err)
+}
+
+// appError maps err to an AppError, 404 for not found, 409 for duplicate key,
+// retryable 503 for timeouts and network errors and 500 otherwise
+func appError(collection string, operation string, err error) errors.AppError {
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return errors.NewAppError("Document not found in "+collection, http.StatusNotFound, err)
+	case isDuplicateKeyError(err):
+		return errors.NewAppError("Duplicate document in "+collection, http.StatusConflict, err)
+	case isTransientError(err):
+		return errors.NewRetryableAppError("Unable to "+operation+" document in "+collection,
+			http.StatusServiceUnavailable, err)
+	default:
+		return errors.NewAppError("Unable to "+operation+" document in "+collection,
+			http.StatusInternalServerError, err)
+	}
+}
+
+func toDocument(value interface{}) (bson.D, error) {
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var document bson.D
+	err = bson.Unmarshal(raw, &document)
+	return document, err
+}
+
+func fromDocument(document bson.D, value interface{}) error {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, value)
+}
+
+func lookupField(document bson.D, key string) (interface{}, bool) {
+	for _, element := range document {
+		if element.Key == key {
+			return element.Value, true
+		}
+	}
+	return nil, false
+}
+
+func setField(document bson.D, key string, value interface{}) bson.D {
+	for i, element := range document {
+		if element.Key == key {
+			document[i].Value = value
+			return document
+		}
+	}
+	return append(document, bson.E{Key: key, Value: value})
+}
+
+func removeField(document bson.D, key string) bson.D {
+	for i, element := range document {
+		if element.Key == key {
+			return append(document[:i], document[i+1:]...)
+		}
+	}
+	return document
+}
+
+func isZeroID(id interface{}) bool {
+	switch id := id.(type) {
+	case nil:
+		return true
+	case primitive.ObjectID:
+		return id == primitive.NilObjectID
+	case string:
+		return id == ""
+	default:
+		return false
+	}
+}
+
+// encodeCursor encodes the _id of the last document of a page
+func encodeCursor(id bson.RawValue) string {
+	return base64.RawURLEncoding.EncodeToString(append([]byte{byte(id.Type)}, id.Value...))
+}
+
+func decodeCursor(cursor string) (bson.RawValue, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	if len(raw) < 2 {
+		return bson.RawValue{}, errors.NewAppError("Page cursor is too short", http.StatusBadRequest, nil)
+	}
+	value := bson.RawValue{Type: bsontype.Type(raw[0]), Value: raw[1:]}
+	return value, validateValue(value)
+}
+
+// validateValue checks that value is well formed by validating a document
+// made of it
+func validateValue(value bson.RawValue) error {
+	document := make([]byte, 4, 4+1+2+len(value.Value)+1)
+	document = append(document, byte(value.Type), 'v', 0)
+	document = append(document, value.Value...)
+	document = append(document, 0)
+	binary.LittleEndian.PutUint32(document, uint32(len(document)))
+	return bson.Raw(document).Validate()
+}
+
+// currentDate returns the current time as a BSON date, truncated to milliseconds
+func currentDate() primitive.DateTime {
+	return primitive.DateTime(time.Now().UnixNano() / int64(time.Millisecond))
+}
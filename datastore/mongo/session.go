@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go/log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// transaction error labels
+const (
+	TransientTransactionError      = "TransientTransactionError"
+	UnknownTransactionCommitResult = "UnknownTransactionCommitResult"
+)
+
+// transactionTimeout is the time after which failed transactions are not
+// retried anymore, same as the driver
+const transactionTimeout = 120 * time.Second
+
+// Session is a wrapper on mongo.Session with tracing Capability
+type Session struct {
+	mongo.Session
+	instrument *instrumentation
+}
+
+// StartSession starts a new session with tracing Capability
+func (client *Client) StartSession(opts ...*options.SessionOptions) (*Session, error) {
+	session, err := client.Client.StartSession(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{session, client.instrument}, nil
+}
+
+// UseSession is a tracing wrapper around mongo client UseSession. The context
+// passed to fn carries both the session and the span.
+func (client *Client) UseSession(ctx context.Context, fn func(mongo.SessionContext) error) error {
+	return client.UseSessionWithOptions(ctx, options.Session(), fn)
+}
+
+// UseSessionWithOptions is a tracing wrapper around mongo client
+// UseSessionWithOptions. The context passed to fn carries both the session
+// and the span.
+func (client *Client) UseSessionWithOptions(ctx context.Context, opts *options.SessionOptions,
+	fn func(mongo.SessionContext) error) error {
+	session, err := client.StartSession(opts)
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	ctx, call := client.instrument.startCall(ctx, "", "Session")
+	err = mongo.WithSession(ctx, session.Session, fn)
+	call.finish(ctx, nil, err)
+	return err
+}
+
+// WithTransaction runs fn in a new session and transaction, see
+// Session.WithTransaction
+func (client *Client) WithTransaction(ctx context.Context, fn func(mongo.SessionContext) (interface{}, error),
+	opts ...*options.TransactionOptions) (interface{}, error) {
+	session, err := client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(context.Background())
+	return session.WithTransaction(ctx, fn, opts...)
+}
+
+// WithTransaction is a tracing wrapper around mongo session WithTransaction.
+// The context passed to fn carries both the session and the transaction span.
+// The transaction is retried on TransientTransactionError and the commit on
+// UnknownTransactionCommitResult for up to 120 seconds, so fn must be
+// idempotent. Every commit and abort is logged on the span.
+func (session *Session) WithTransaction(ctx context.Context, fn func(mongo.SessionContext) (interface{}, error),
+	opts ...*options.TransactionOptions) (interface{}, error) {
+	ctx, call := session.instrument.startCall(ctx, "", "Transaction")
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if err := session.StartTransaction(opts...); err != nil {
+			call.finish(ctx, nil, err)
+			return nil, err
+		}
+
+		var result interface{}
+		err := mongo.WithSession(ctx, session.Session, func(sessionContext mongo.SessionContext) error {
+			var fnErr error
+			result, fnErr = fn(sessionContext)
+			return fnErr
+		})
+		if err != nil {
+			abortErr := session.AbortTransaction(context.Background())
+			call.logTransaction("abort", attempt, err)
+			if hasErrorLabel(err, TransientTransactionError) && time.Since(start) < transactionTimeout {
+				continue
+			}
+			if abortErr != nil {
+				call.logTransaction("abort.failed", attempt, abortErr)
+			}
+			call.setTag("db.transaction.outcome", "aborted")
+			call.setTag("db.transaction.attempts", attempt)
+			call.finish(ctx, nil, err)
+			return nil, err
+		}
+
+		for {
+			err = session.CommitTransaction(ctx)
+			if err == nil {
+				call.logTransaction("commit", attempt, nil)
+				call.setTag("db.transaction.outcome", "committed")
+				call.setTag("db.transaction.attempts", attempt)
+				call.finish(ctx, nil, nil)
+				return result, nil
+			}
+			call.logTransaction("commit.failed", attempt, err)
+			if time.Since(start) >= transactionTimeout || !hasErrorLabel(err, UnknownTransactionCommitResult) {
+				break
+			}
+		}
+
+		if hasErrorLabel(err, TransientTransactionError) && time.Since(start) < transactionTimeout {
+			continue
+		}
+		call.setTag("db.transaction.outcome", "aborted")
+		call.setTag("db.transaction.attempts", attempt)
+		call.finish(ctx, nil, err)
+		return nil, err
+	}
+}
+
+func (c *call) logTransaction(event string, attempt int, err error) {
+	if err == nil {
+		c.logFields(log.String("event", "transaction."+event), log.Int("attempt", attempt))
+		return
+	}
+	c.logFields(log.String("event", "transaction."+event), log.Int("attempt", attempt),
+		log.String("message", err.Error()))
+}
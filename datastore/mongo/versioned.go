@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+)
+
+// VersionField is the document field used for optimistic concurrency control
+const VersionField = "version"
+
+// UpdateVersioned updates the document matching filter only if its version is
+// still version and increments the version. A 409 AppError is returned when
+// no document matched, i.e. the document was changed or deleted meanwhile.
+// Documents without version field are at version 0.
+func (collection *Collection) UpdateVersioned(ctx context.Context, filter interface{}, version int64,
+	update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, errors.AppError) {
+	updateDocument, err := toDocument(update)
+	if err != nil {
+		return nil, appError(collection.Name(), "encode", err)
+	}
+	updateDocument = incrementVersion(updateDocument)
+
+	result, err := collection.UpdateOne(ctx, versionFilter(filter, version), updateDocument, opts...)
+	if err != nil {
+		return nil, appError(collection.Name(), "update", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, versionConflict(collection.Name())
+	}
+	return result, nil
+}
+
+// ReplaceVersioned replaces the document matching filter only if its version
+// is still version. The replacement is stored with version + 1. A 409
+// AppError is returned when no document matched.
+func (collection *Collection) ReplaceVersioned(ctx context.Context, filter interface{}, version int64,
+	replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, errors.AppError) {
+	document, err := toDocument(replacement)
+	if err != nil {
+		return nil, appError(collection.Name(), "encode", err)
+	}
+	document = setField(document, VersionField, version+1)
+
+	result, err := collection.ReplaceOne(ctx, versionFilter(filter, version), document, opts...)
+	if err != nil {
+		return nil, appError(collection.Name(), "replace", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, versionConflict(collection.Name())
+	}
+	return result, nil
+}
+
+// RetryOnConflict loads the document matching filter, applies mutate and
+// replaces it if its version did not change. On a version conflict the document is
+// loaded again and mutate is re-applied, up to maxAttempts times. mutate must
+// only change the document it is given. The stored document is returned.
+func RetryOnConflict[T any](ctx context.Context, collection *Collection, filter interface{}, maxAttempts int,
+	mutate func(document *T) errors.AppError) (*T, errors.AppError) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		var raw bson.Raw
+		if err := collection.FindOne(ctx, filter).Decode(&raw); err != nil {
+			return nil, appError(collection.Name(), "find", err)
+		}
+		var document T
+		if err := bson.Unmarshal(raw, &document); err != nil {
+			return nil, appError(collection.Name(), "decode", err)
+		}
+		version, _ := raw.Lookup(VersionField).AsInt64OK()
+
+		if err := mutate(&document); err != nil {
+			return nil, err
+		}
+		replacement, err := toDocument(&document)
+		if err != nil {
+			return nil, appError(collection.Name(), "encode", err)
+		}
+		replacement = setField(replacement, VersionField, version+1)
+		result, err := collection.ReplaceOne(ctx, versionFilter(filter, version), replacement)
+		if err != nil {
+			return nil, appError(collection.Name(), "replace", err)
+		}
+		if result.MatchedCount > 0 {
+			if err := fromDocument(replacement, &document); err != nil {
+				return nil, appError(collection.Name(), "decode", err)
+			}
+			return &document, nil
+		}
+
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			span.LogFields(
+				log.String("event", "version.conflict"),
+				log.String("collection", collection.Name()),
+				log.Int("attempt", attempt),
+				log.Int64("version", version),
+			)
+		}
+		if attempt >= maxAttempts {
+			return nil, versionConflict(collection.Name())
+		}
+	}
+}
+
+// versionFilter restricts filter to the documents at version
+func versionFilter(filter interface{}, version int64) bson.D {
+	var condition interface{} = version
+	if version == 0 {
+		condition = bson.M{"$in": bson.A{0, nil}}
+	}
+	return bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: VersionField, Value: condition}}}}}
+}
+
+// incrementVersion adds the version increment to the $inc of update
+func incrementVersion(update bson.D) bson.D {
+	for i, element := range update {
+		if element.Key != "$inc" {
+			continue
+		}
+		if inc, ok := element.Value.(bson.D); ok {
+			update[i].Value = setField(inc, VersionField, 1)
+			return update
+		}
+	}
+	return append(update, bson.E{Key: "$inc", Value: bson.D{{Key: VersionField, Value: 1}}})
+}
+
+func versionConflict(collection string) errors.AppError {
+	return errors.NewAppError("Document in "+collection+" was modified concurrently", http.StatusConflict, nil)
+}
@@ -0,0 +1,199 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/async"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/timeutil"
+	"github.com/dhyaniarun1993/foody-common/logger"
+	"github.com/dhyaniarun1993/foody-common/retry"
+)
+
+// PubSubOptions configures a PubSubManager, zero values use the defaults
+type PubSubOptions struct {
+	// Concurrency is the number of messages handled at once, 10 by default
+	Concurrency int
+	// PingInterval is how long the subscription may stay idle before the
+	// connection is checked, 30s by default
+	PingInterval time.Duration
+	// Backoff is the wait between resubscribe attempts, exponential from
+	// 100ms to 10s by default
+	Backoff retry.Backoff
+
+	// Logger logs the handler failures and the lost subscriptions when set
+	Logger *logger.Logger
+}
+
+type channelHandler func(ctx context.Context, message *redis.Message) errors.AppError
+
+// PubSubManager keeps the subscription to a set of channels alive, it
+// subscribes again whenever the connection is lost. Messages are decoded and
+// handled concurrently, each in its own span.
+type PubSubManager struct {
+	client   *redis.Client
+	tracer   opentracing.Tracer
+	options  PubSubOptions
+	mu       sync.Mutex
+	handlers map[string]channelHandler
+}
+
+// NewPubSubManager creates a PubSubManager
+func NewPubSubManager(client *redis.Client, tracer opentracing.Tracer, options PubSubOptions) *PubSubManager {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 10
+	}
+	if options.PingInterval <= 0 {
+		options.PingInterval = 30 * time.Second
+	}
+	if options.Backoff == nil {
+		options.Backoff = retry.ExponentialBackoff(100*time.Millisecond, 10*time.Second)
+	}
+	return &PubSubManager{
+		client:   client,
+		tracer:   tracer,
+		options:  options,
+		handlers: map[string]channelHandler{},
+	}
+}
+
+// Handle registers handler for the JSON messages of channel, it must be
+// called before Run. Messages that cannot be decoded into T are logged and
+// dropped.
+func Handle[T any](manager *PubSubManager, channel string,
+	handler func(ctx context.Context, channel string, message T) errors.AppError) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.handlers[channel] = func(ctx context.Context, message *redis.Message) errors.AppError {
+		var payload T
+		if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil {
+			return errors.NewAppError("Unable to decode message of channel "+message.Channel, http.StatusBadRequest, err)
+		}
+		return handler(ctx, message.Channel, payload)
+	}
+}
+
+// PublishJSON publishes message encoded as JSON to channel
+func PublishJSON(ctx context.Context, client *redis.Client, channel string, message interface{}) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return client.WithContext(ctx).Publish(channel, payload).Err()
+}
+
+// Run subscribes to the registered channels and handles messages until ctx
+// is cancelled, it waits for the running handlers before returning
+func (manager *PubSubManager) Run(ctx context.Context) errors.AppError {
+	manager.mu.Lock()
+	channels := make([]string, 0, len(manager.handlers))
+	for channel := range manager.handlers {
+		channels = append(channels, channel)
+	}
+	manager.mu.Unlock()
+	if len(channels) == 0 {
+		return errors.NewAppError("No Redis channel handler registered", http.StatusInternalServerError, nil)
+	}
+
+	group, groupCtx := async.WithContext(ctx)
+	slots := make(chan struct{}, manager.options.Concurrency)
+	var wait time.Duration
+	for attempt := 1; ctx.Err() == nil; attempt++ {
+		pubsub := manager.client.Subscribe(channels...)
+		err := manager.receive(ctx, pubsub, func(message *redis.Message) {
+			attempt, wait = 0, 0
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			group.Go(func() errors.AppError {
+				defer func() { <-slots }()
+				manager.dispatch(groupCtx, message)
+				return nil
+			})
+		})
+		pubsub.Close()
+		if ctx.Err() != nil {
+			break
+		}
+
+		wait = manager.options.Backoff.Next(attempt, wait)
+		manager.logError(ctx, err, "Redis subscription lost, subscribing again", zap.Duration("wait", wait))
+		timeutil.Sleep(ctx, wait)
+	}
+	group.Wait()
+	return nil
+}
+
+// receive reads pubsub until the connection fails or ctx is cancelled
+func (manager *PubSubManager) receive(ctx context.Context, pubsub *redis.PubSub, handle func(*redis.Message)) error {
+	if _, err := pubsub.Receive(); err != nil {
+		return err
+	}
+	lastActivity := time.Now()
+	for ctx.Err() == nil {
+		received, err := pubsub.ReceiveTimeout(time.Second)
+		if err != nil {
+			if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+				return err
+			}
+			if time.Since(lastActivity) >= manager.options.PingInterval {
+				if err := pubsub.Ping(); err != nil {
+					return err
+				}
+				lastActivity = time.Now()
+			}
+			continue
+		}
+		lastActivity = time.Now()
+		if message, ok := received.(*redis.Message); ok {
+			handle(message)
+		}
+	}
+	return nil
+}
+
+// dispatch handles message in its own span and logs the failure
+func (manager *PubSubManager) dispatch(ctx context.Context, message *redis.Message) {
+	manager.mu.Lock()
+	handler, ok := manager.handlers[message.Channel]
+	manager.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	span := manager.tracer.StartSpan("RedisPubSub." + message.Channel)
+	defer span.Finish()
+	ext.SpanKind.Set(span, ext.SpanKindConsumerEnum)
+	ext.Component.Set(span, "redis.PubSub")
+	ext.MessageBusDestination.Set(span, message.Channel)
+	spanCtx := opentracing.ContextWithSpan(ctx, span)
+
+	if err := handler(spanCtx, message); err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(
+			log.String("event", "error"),
+			log.Int("status", err.StatusCode()),
+			log.String("message", err.Error()),
+		)
+		manager.logError(spanCtx, err, "Unable to handle Redis message", zap.String("channel", message.Channel))
+	}
+}
+
+func (manager *PubSubManager) logError(ctx context.Context, err error, message string, fields ...zap.Field) {
+	if manager.options.Logger != nil {
+		manager.options.Logger.WithContext(ctx).WithError(err).Error(message, fields...)
+	}
+}
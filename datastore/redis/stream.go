@@ -0,0 +1,287 @@
+package redis
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/timeutil"
+	"github.com/dhyaniarun1993/foody-common/logger"
+)
+
+// headerPrefix marks the stream fields that carry trace headers
+const headerPrefix = "header:"
+
+// dead letter fields
+const (
+	DeadLetterStreamField     = "dead-letter:stream"
+	DeadLetterIDField         = "dead-letter:id"
+	DeadLetterDeliveriesField = "dead-letter:deliveries"
+)
+
+// StreamMessage is a message read from a stream by a consumer group
+type StreamMessage struct {
+	ID     string
+	Values map[string]interface{}
+	// Deliveries is the number of times the message was delivered, 1 the
+	// first time
+	Deliveries int64
+}
+
+// StreamHandler handles a stream message, the message is acknowledged when
+// no error is returned and delivered again otherwise
+type StreamHandler func(ctx context.Context, message StreamMessage) errors.AppError
+
+// StreamWorkerOptions configures a StreamWorker, zero values use the defaults
+type StreamWorkerOptions struct {
+	Stream   string
+	Group    string
+	Consumer string
+
+	// BatchSize is the number of messages read at once, 10 by default
+	BatchSize int64
+	// Block is how long a read waits for new messages, 5s by default
+	Block time.Duration
+	// ClaimMinIdle is how long a message stays pending before it is claimed
+	// and delivered again, 1m by default. It must be longer than the handler
+	// takes or messages are handled twice.
+	ClaimMinIdle time.Duration
+	// ClaimInterval is how often pending messages are checked, 30s by default
+	ClaimInterval time.Duration
+	// MaxDeliveries moves a message to the dead letter stream once it was
+	// delivered that many times, 5 by default
+	MaxDeliveries int64
+	// DeadLetterStream is Stream + ".dead-letter" by default
+	DeadLetterStream string
+
+	// Logger logs the failed messages when set
+	Logger *logger.Logger
+}
+
+// StreamWorker processes the messages of a stream as a member of a consumer
+// group. Failed messages and messages of dead consumers are claimed after
+// ClaimMinIdle and delivered again, poison messages are moved to the dead
+// letter stream.
+type StreamWorker struct {
+	client  *redis.Client
+	tracer  opentracing.Tracer
+	options StreamWorkerOptions
+	handler StreamHandler
+}
+
+// NewStreamWorker creates a StreamWorker
+func NewStreamWorker(client *redis.Client, tracer opentracing.Tracer, options StreamWorkerOptions,
+	handler StreamHandler) *StreamWorker {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 10
+	}
+	if options.Block <= 0 {
+		options.Block = 5 * time.Second
+	}
+	if options.ClaimMinIdle <= 0 {
+		options.ClaimMinIdle = time.Minute
+	}
+	if options.ClaimInterval <= 0 {
+		options.ClaimInterval = 30 * time.Second
+	}
+	if options.MaxDeliveries <= 0 {
+		options.MaxDeliveries = 5
+	}
+	if options.DeadLetterStream == "" {
+		options.DeadLetterStream = options.Stream + ".dead-letter"
+	}
+	return &StreamWorker{client, tracer, options, handler}
+}
+
+// PublishStream adds values to stream with the span context of ctx in the headers
+// and returns the message ID
+func PublishStream(ctx context.Context, client *redis.Client, tracer opentracing.Tracer, stream string,
+	values map[string]interface{}) (string, error) {
+	fields := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		fields[name] = value
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		headers := opentracing.TextMapCarrier{}
+		tracer.Inject(span.Context(), opentracing.TextMap, headers)
+		for name, value := range headers {
+			fields[headerPrefix+name] = value
+		}
+	}
+	return client.WithContext(ctx).XAdd(&redis.XAddArgs{Stream: stream, Values: fields}).Result()
+}
+
+// Run creates the consumer group if needed and processes messages until ctx
+// is cancelled. Run returns at most Block after ctx is cancelled.
+func (worker *StreamWorker) Run(ctx context.Context) errors.AppError {
+	client := worker.client.WithContext(ctx)
+	err := client.XGroupCreateMkStream(worker.options.Stream, worker.options.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.NewAppError("Unable to create consumer group "+worker.options.Group,
+			http.StatusInternalServerError, err)
+	}
+
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= worker.options.ClaimInterval {
+			if err := worker.claim(ctx); err != nil {
+				worker.logError(ctx, err, "Unable to claim pending stream messages")
+			}
+			lastClaim = time.Now()
+		}
+
+		streams, err := client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    worker.options.Group,
+			Consumer: worker.options.Consumer,
+			Streams:  []string{worker.options.Stream, ">"},
+			Count:    worker.options.BatchSize,
+			Block:    worker.options.Block,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			worker.logError(ctx, err, "Unable to read stream")
+			timeutil.Sleep(ctx, time.Second)
+			continue
+		}
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				worker.process(ctx, message, 1)
+			}
+		}
+	}
+	return nil
+}
+
+// claim delivers the messages that are pending for longer than ClaimMinIdle
+// to this consumer again and moves the poison messages to the dead letter
+// stream
+func (worker *StreamWorker) claim(ctx context.Context) error {
+	client := worker.client.WithContext(ctx)
+	pending, err := client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: worker.options.Stream,
+		Group:  worker.options.Group,
+		Start:  "-",
+		End:    "+",
+		Count:  100,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range pending {
+		if entry.Idle < worker.options.ClaimMinIdle {
+			continue
+		}
+		messages, err := client.XClaim(&redis.XClaimArgs{
+			Stream:   worker.options.Stream,
+			Group:    worker.options.Group,
+			Consumer: worker.options.Consumer,
+			MinIdle:  worker.options.ClaimMinIdle,
+			Messages: []string{entry.Id},
+		}).Result()
+		if err != nil {
+			return err
+		}
+		for _, message := range messages {
+			if entry.RetryCount >= worker.options.MaxDeliveries {
+				if err := worker.deadLetter(ctx, message, entry.RetryCount); err != nil {
+					return err
+				}
+				continue
+			}
+			// XCLAIM increments the delivery count
+			worker.process(ctx, message, entry.RetryCount+1)
+		}
+	}
+	return nil
+}
+
+// process handles message in a span that continues the trace of the
+// producer and acknowledges it on success
+func (worker *StreamWorker) process(ctx context.Context, message redis.XMessage, deliveries int64) {
+	streamMessage, headers := splitHeaders(message, deliveries)
+	var spanOpts []opentracing.StartSpanOption
+	if producer, err := worker.tracer.Extract(opentracing.TextMap, headers); err == nil {
+		spanOpts = append(spanOpts, opentracing.ChildOf(producer))
+	}
+	span := worker.tracer.StartSpan("RedisStream."+worker.options.Stream, spanOpts...)
+	defer span.Finish()
+	ext.SpanKind.Set(span, ext.SpanKindConsumerEnum)
+	ext.Component.Set(span, "redis.Stream")
+	ext.MessageBusDestination.Set(span, worker.options.Stream)
+	span.SetTag("message.id", message.ID)
+	span.SetTag("message.deliveries", deliveries)
+	span.SetTag("redis.group", worker.options.Group)
+	span.SetTag("redis.consumer", worker.options.Consumer)
+	spanCtx := opentracing.ContextWithSpan(ctx, span)
+
+	if err := worker.handler(spanCtx, streamMessage); err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(
+			log.String("event", "error"),
+			log.Int("status", err.StatusCode()),
+			log.String("message", err.Error()),
+		)
+		worker.logError(spanCtx, err, "Unable to handle stream message")
+		return
+	}
+	if err := worker.client.WithContext(ctx).XAck(worker.options.Stream, worker.options.Group, message.ID).Err(); err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.String("event", "error"), log.String("message", err.Error()))
+	}
+}
+
+// deadLetter adds message to the dead letter stream and acknowledges it
+func (worker *StreamWorker) deadLetter(ctx context.Context, message redis.XMessage, deliveries int64) error {
+	values := make(map[string]interface{}, len(message.Values)+3)
+	for name, value := range message.Values {
+		values[name] = value
+	}
+	values[DeadLetterStreamField] = worker.options.Stream
+	values[DeadLetterIDField] = message.ID
+	values[DeadLetterDeliveriesField] = strconv.FormatInt(deliveries, 10)
+
+	client := worker.client.WithContext(ctx)
+	if err := client.XAdd(&redis.XAddArgs{Stream: worker.options.DeadLetterStream, Values: values}).Err(); err != nil {
+		return err
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.LogFields(log.String("event", "dead-letter"), log.String("message.id", message.ID))
+	}
+	return client.XAck(worker.options.Stream, worker.options.Group, message.ID).Err()
+}
+
+func (worker *StreamWorker) logError(ctx context.Context, err error, message string) {
+	if worker.options.Logger != nil {
+		worker.options.Logger.WithContext(ctx).WithError(err).Error(message)
+	}
+}
+
+// splitHeaders separates the trace headers from the values of message
+func splitHeaders(message redis.XMessage, deliveries int64) (StreamMessage, opentracing.TextMapCarrier) {
+	streamMessage := StreamMessage{ID: message.ID, Values: map[string]interface{}{}, Deliveries: deliveries}
+	headers := opentracing.TextMapCarrier{}
+	for name, value := range message.Values {
+		if strings.HasPrefix(name, headerPrefix) {
+			if s, ok := value.(string); ok {
+				headers[strings.TrimPrefix(name, headerPrefix)] = s
+			}
+			continue
+		}
+		streamMessage.Values[name] = value
+	}
+	return streamMessage, headers
+}
@@ -0,0 +1,194 @@
+package sanitizer
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// sanitizer constants
+const (
+	Placeholder = "?"
+	truncated   = "..."
+)
+
+// Configuration provides configuration for sanitizing DB statements before
+// they are added to spans and logs
+type Configuration struct {
+	// MaxLength truncates the statement, 0 means no limit
+	MaxLength int `split_words:"true" default:"1024"`
+	// KeepValues keeps the values of the fields that are not denied
+	KeepValues bool `split_words:"true"`
+	// DeniedFields are always replaced by the placeholder, matched case insensitively
+	DeniedFields []string `split_words:"true"`
+}
+
+// Sanitizer replaces sensitive values in SQL queries and BSON documents with
+// placeholders while keeping the field names
+type Sanitizer struct {
+	maxLength    int
+	keepValues   bool
+	deniedFields map[string]bool
+	deniedRegex  *regexp.Regexp
+}
+
+// New creates a Sanitizer
+func New(configuration Configuration) *Sanitizer {
+	sanitizer := &Sanitizer{
+		maxLength:    configuration.MaxLength,
+		keepValues:   configuration.KeepValues,
+		deniedFields: map[string]bool{},
+	}
+	var quoted []string
+	for _, field := range configuration.DeniedFields {
+		sanitizer.deniedFields[strings.ToLower(field)] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	if len(quoted) > 0 {
+		sanitizer.deniedRegex = regexp.MustCompile(`(?i)(\b(?:` + strings.Join(quoted, "|") +
+			`)\b[` + "`" + `"]?\s*(?:=|<>|!=|<=|>=|<|>|\bLIKE\b)\s*)('(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"|[0-9]+(?:\.[0-9]+)?)`)
+	}
+	return sanitizer
+}
+
+// SQL sanitizes query. Literals are replaced unless values are kept, in which
+// case only the literals compared with denied columns are replaced.
+func (sanitizer *Sanitizer) SQL(query string) string {
+	if !sanitizer.keepValues {
+		return sanitizer.Truncate(NormalizeSQL(query))
+	}
+	if sanitizer.deniedRegex != nil {
+		query = sanitizer.deniedRegex.ReplaceAllString(query, "${1}"+Placeholder)
+	}
+	return sanitizer.Truncate(query)
+}
+
+// BSON sanitizes a document, a slice of documents or any value that can be
+// marshalled to BSON. Values are replaced unless they are kept, field names
+// are always kept and values of denied fields are always replaced.
+func (sanitizer *Sanitizer) BSON(value interface{}) string {
+	return sanitizer.Truncate(sanitizer.render(value))
+}
+
+// Truncate cuts s to the configured max length
+func (sanitizer *Sanitizer) Truncate(s string) string {
+	if sanitizer.maxLength <= 0 || len(s) <= sanitizer.maxLength {
+		return s
+	}
+	cut := sanitizer.maxLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + truncated
+}
+
+func (sanitizer *Sanitizer) render(value interface{}) string {
+	if value == nil {
+		return "nil"
+	}
+	if raw, err := bson.Marshal(value); err == nil {
+		return sanitizer.renderDocument(raw)
+	}
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Slice || reflectValue.Kind() == reflect.Array {
+		parts := make([]string, reflectValue.Len())
+		for i := range parts {
+			parts[i] = sanitizer.render(reflectValue.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	if sanitizer.keepValues {
+		return fmt.Sprint(value)
+	}
+	return Placeholder
+}
+
+func (sanitizer *Sanitizer) renderDocument(document bson.Raw) string {
+	elements, err := document.Elements()
+	if err != nil {
+		return Placeholder
+	}
+	parts := make([]string, len(elements))
+	for i, element := range elements {
+		key := element.Key()
+		if sanitizer.deniedFields[strings.ToLower(key)] {
+			parts[i] = key + ": " + Placeholder
+			continue
+		}
+		parts[i] = key + ": " + sanitizer.renderValue(element.Value())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (sanitizer *Sanitizer) renderValue(value bson.RawValue) string {
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		return sanitizer.renderDocument(value.Document())
+	case bsontype.Array:
+		values, err := value.Array().Values()
+		if err != nil || len(values) == 0 {
+			return "[]"
+		}
+		if !sanitizer.keepValues {
+			return "[" + sanitizer.renderValue(values[0]) + "]"
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = sanitizer.renderValue(v)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		if sanitizer.keepValues {
+			return renderScalar(value)
+		}
+		return Placeholder
+	}
+}
+
+// renderScalar renders the common scalar types in plain form and the rest as
+// extended JSON
+func renderScalar(value bson.RawValue) string {
+	switch value.Type {
+	case bsontype.String:
+		return strconv.Quote(value.StringValue())
+	case bsontype.Int32:
+		return strconv.FormatInt(int64(value.Int32()), 10)
+	case bsontype.Int64:
+		return strconv.FormatInt(value.Int64(), 10)
+	case bsontype.Double:
+		return strconv.FormatFloat(value.Double(), 'g', -1, 64)
+	case bsontype.Boolean:
+		return strconv.FormatBool(value.Boolean())
+	case bsontype.Null:
+		return "null"
+	case bsontype.ObjectID:
+		return "ObjectId(" + strconv.Quote(value.ObjectID().Hex()) + ")"
+	case bsontype.DateTime:
+		return value.Time().UTC().Format(time.RFC3339Nano)
+	default:
+		return value.String()
+	}
+}
+
+var (
+	stringLiteralRegex = regexp.MustCompile(`'(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"`)
+	numberLiteralRegex = regexp.MustCompile(`\b[0-9]+(?:\.[0-9]+)?\b`)
+	inListRegex        = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+	whitespaceRegex    = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeSQL replaces literals with ? and collapses whitespace so that
+// calls of the same statement can be grouped together
+func NormalizeSQL(query string) string {
+	query = stringLiteralRegex.ReplaceAllString(query, Placeholder)
+	query = numberLiteralRegex.ReplaceAllString(query, Placeholder)
+	query = inListRegex.ReplaceAllString(query, "("+Placeholder+")")
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(query, " "))
+}
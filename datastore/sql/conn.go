@@ -3,114 +3,88 @@ package sql
 import (
 	"context"
 	"database/sql"
-	"fmt"
-
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 )
 
 // Conn is a wrapper on sql.Conn with tracing Capability
 type Conn struct {
 	*sql.Conn
-	tracer opentracing.Tracer
+	instrument *instrumentation
 }
 
 // BeginTx instruments the sql.DB BeginTx with tracing capability
-func (conn *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := conn.tracer.StartSpan(
-			fmt.Sprintf("conn.beginTxn"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return conn.Conn.BeginTx(ctx, opts)
+func (conn *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	ctx, call := conn.instrument.startCall(ctx, "conn.beginTxn", "")
+	tx, err := conn.Conn.BeginTx(ctx, opts)
+	call.finish(ctx, -1, err)
+	return &Tx{tx, conn.instrument}, err
 }
 
 // ExecContext instruments the sql.DB ExecContext with tracing capability
 func (conn *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := conn.tracer.StartSpan(
-			fmt.Sprintf("conn.execute"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return conn.Conn.ExecContext(ctx, query, args...)
+	ctx, call := conn.instrument.startCall(ctx, "conn.execute", query)
+	result, err := conn.Conn.ExecContext(ctx, query, args...)
+	call.finish(ctx, rowsAffected(result), err)
+	return result, err
 }
 
 // PingContext instruments the sql.DB PingContext with tracing capability
 func (conn *Conn) PingContext(ctx context.Context) error {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := conn.tracer.StartSpan(
-			fmt.Sprintf("conn.ping"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return conn.Conn.PingContext(ctx)
+	ctx, call := conn.instrument.startCall(ctx, "conn.ping", "")
+	err := conn.Conn.PingContext(ctx)
+	call.finish(ctx, -1, err)
+	return err
 }
 
 // PrepareContext instruments the sql.DB PrepareContext with tracing capability
 func (conn *Conn) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := conn.tracer.StartSpan(
-			fmt.Sprintf("db.prepare"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
+	ctx, call := conn.instrument.startCall(ctx, "db.prepare", query)
 	stmt, err := conn.Conn.PrepareContext(ctx, query)
-	return &Stmt{stmt, conn.tracer}, err
+	call.finish(ctx, -1, err)
+	return &Stmt{stmt, query, conn.instrument}, err
 }
 
 // QueryContext instruments the sql.DB QueryContext with tracing capability
 func (conn *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := conn.tracer.StartSpan(
-			fmt.Sprintf("db.query"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return conn.Conn.QueryContext(ctx, query, args...)
+	ctx, call := conn.instrument.startCall(ctx, "db.query", query)
+	rows, err := conn.Conn.QueryContext(ctx, query, args...)
+	call.finish(ctx, -1, err)
+	return rows, err
 }
 
 // QueryRowContext instruments the sql.DB QueryRowContext with tracing capability
 func (conn *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := conn.tracer.StartSpan(
-			fmt.Sprintf("db.queryRow"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
+	ctx, call := conn.instrument.startCall(ctx, "db.queryRow", query)
+	row := conn.Conn.QueryRowContext(ctx, query, args...)
+	call.finish(ctx, -1, row.Err())
+	return row
+}
+
+// SelectContext runs the query and scans all the rows into dest, a pointer to
+// slice, mapping columns to struct fields by db tag
+func (conn *Conn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanAll(rows, dest)
+}
+
+// GetContext runs the query and scans the first row into dest, mapping columns
+// to struct fields by db tag. sql.ErrNoRows is returned if there is no row.
+func (conn *Conn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanOne(rows, dest)
+}
+
+// NamedExecContext executes the query binding :name parameters from arg, a
+// map with string keys or a struct with db tags
+func (conn *Conn) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	boundQuery, args, err := bindNamed(query, arg)
+	if err != nil {
+		return nil, err
 	}
-	return conn.Conn.QueryRowContext(ctx, query, args...)
+	return conn.ExecContext(ctx, boundQuery, args...)
 }
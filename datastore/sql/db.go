@@ -3,132 +3,114 @@ package sql
 import (
 	"context"
 	"database/sql"
-	"fmt"
-
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 )
 
 // DB is a wrapper on sql.DB with tracing Capability
 type DB struct {
 	*sql.DB
-	tracer opentracing.Tracer
+	instrument *instrumentation
+	replicas   *replicaSet
 }
 
-// BeginTx instruments the sql.DB BeginTx with tracing capability
-func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := db.tracer.StartSpan(
-			fmt.Sprintf("db.beginTxn"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
+// Close closes the primary and the replica pools
+func (db *DB) Close() error {
+	if db.replicas != nil {
+		if err := db.replicas.close(); err != nil {
+			db.DB.Close()
+			return err
+		}
 	}
-	return db.DB.BeginTx(ctx, opts)
+	return db.DB.Close()
+}
+
+// BeginTx instruments the sql.DB BeginTx with tracing capability
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	ctx, call := db.instrument.startCall(ctx, "db.beginTxn", "")
+	tx, err := db.DB.BeginTx(ctx, opts)
+	call.finish(ctx, -1, err)
+	return &Tx{tx, db.instrument}, err
 }
 
 // Conn instruments the sql.DB Conn with tracing capability
 func (db *DB) Conn(ctx context.Context) (*Conn, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := db.tracer.StartSpan(
-			fmt.Sprintf("db.conn"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
+	ctx, call := db.instrument.startCall(ctx, "db.conn", "")
 	conn, err := db.DB.Conn(ctx)
-	return &Conn{conn, db.tracer}, err
+	call.finish(ctx, -1, err)
+	return &Conn{conn, db.instrument}, err
 }
 
 // ExecContext instruments the sql.DB ExecContext with tracing capability
 func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := db.tracer.StartSpan(
-			fmt.Sprintf("db.execute"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return db.DB.ExecContext(ctx, query, args...)
+	ctx, call := db.instrument.startCall(ctx, "db.execute", query)
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	call.finish(ctx, rowsAffected(result), err)
+	return result, err
 }
 
 // PingContext instruments the sql.DB PingContext with tracing capability
 func (db *DB) PingContext(ctx context.Context) error {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := db.tracer.StartSpan(
-			fmt.Sprintf("db.ping"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return db.DB.PingContext(ctx)
+	ctx, call := db.instrument.startCall(ctx, "db.ping", "")
+	err := db.DB.PingContext(ctx)
+	call.finish(ctx, -1, err)
+	return err
 }
 
 // PrepareContext instruments the sql.DB PrepareContext with tracing capability
 func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := db.tracer.StartSpan(
-			fmt.Sprintf("db.prepare"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
+	ctx, call := db.instrument.startCall(ctx, "db.prepare", query)
 	stmt, err := db.DB.PrepareContext(ctx, query)
-	return &Stmt{stmt, db.tracer}, err
+	call.finish(ctx, -1, err)
+	return &Stmt{stmt, query, db.instrument}, err
 }
 
-// QueryContext instruments the sql.DB QueryContext with tracing capability
+// QueryContext instruments the sql.DB QueryContext with tracing capability.
+// Reads are routed to a healthy replica unless the primary is forced.
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := db.tracer.StartSpan(
-			fmt.Sprintf("db.query"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return db.DB.QueryContext(ctx, query, args...)
+	node, target := db.reader(ctx)
+	ctx, call := db.instrument.startCall(ctx, "db.query", query)
+	call.setTag("db.node", node)
+	rows, err := target.QueryContext(ctx, query, args...)
+	call.finish(ctx, -1, err)
+	return rows, err
 }
 
-// QueryRowContext instruments the sql.DB QueryRowContext with tracing capability
+// QueryRowContext instruments the sql.DB QueryRowContext with tracing capability.
+// Reads are routed to a healthy replica unless the primary is forced.
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := db.tracer.StartSpan(
-			fmt.Sprintf("db.queryRow"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
+	node, target := db.reader(ctx)
+	ctx, call := db.instrument.startCall(ctx, "db.queryRow", query)
+	call.setTag("db.node", node)
+	row := target.QueryRowContext(ctx, query, args...)
+	call.finish(ctx, -1, row.Err())
+	return row
+}
+
+// SelectContext runs the query and scans all the rows into dest, a pointer to
+// slice, mapping columns to struct fields by db tag
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanAll(rows, dest)
+}
+
+// GetContext runs the query and scans the first row into dest, mapping columns
+// to struct fields by db tag. sql.ErrNoRows is returned if there is no row.
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanOne(rows, dest)
+}
+
+// NamedExecContext executes the query binding :name parameters from arg, a
+// map with string keys or a struct with db tags
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	boundQuery, args, err := bindNamed(query, arg)
+	if err != nil {
+		return nil, err
 	}
-	return db.DB.QueryRowContext(ctx, query, args...)
+	return db.ExecContext(ctx, boundQuery, args...)
 }
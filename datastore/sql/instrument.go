@@ -0,0 +1,159 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/datastore/sanitizer"
+	"github.com/dhyaniarun1993/foody-common/logger"
+)
+
+// Option configures the optional instrumentation of the SQL wrappers
+type Option func(*instrumentation)
+
+// WithLogger logs the calls slower than the configured SlowQueryThreshold
+func WithLogger(logger *logger.Logger) Option {
+	return func(i *instrumentation) {
+		i.logger = logger
+	}
+}
+
+// WithIgnoredErrors sets the errors that do not mark the span as failed,
+// sql.ErrNoRows by default
+func WithIgnoredErrors(errs ...error) Option {
+	return func(i *instrumentation) {
+		i.ignoredErrors = errs
+	}
+}
+
+// WithMetrics records per operation latency histograms in factory
+func WithMetrics(factory metrics.Factory) Option {
+	return func(i *instrumentation) {
+		i.metrics = factory.Namespace(metrics.NSOptions{Name: "sql"})
+	}
+}
+
+// instrumentation carries the tracer, the statement sanitizer and the optional
+// logger and metrics shared by DB, Conn, Tx and Stmt
+type instrumentation struct {
+	tracer             opentracing.Tracer
+	sanitizer          *sanitizer.Sanitizer
+	logger             *logger.Logger
+	slowQueryThreshold time.Duration
+	metrics            metrics.Factory
+	timers             sync.Map
+	ignoredErrors      []error
+}
+
+// call tracks a single instrumented call from start to finish
+type call struct {
+	*instrumentation
+	operation string
+	statement string
+	span      opentracing.Span
+	start     time.Time
+}
+
+// startCall starts a child span if ctx has a span and returns the context to
+// pass down to the driver
+func (i *instrumentation) startCall(ctx context.Context, operation string, statement string) (context.Context, *call) {
+	c := &call{instrumentation: i, operation: operation, statement: statement, start: time.Now()}
+	span := opentracing.SpanFromContext(ctx)
+	if span != nil {
+		c.span = i.tracer.StartSpan(
+			operation,
+			opentracing.ChildOf(span.Context()),
+		)
+		ext.Component.Set(c.span, "database/sql")
+		ext.SpanKind.Set(c.span, "client")
+		if statement != "" {
+			ext.DBStatement.Set(c.span, i.sanitizer.SQL(statement))
+		}
+		ctx = opentracing.ContextWithSpan(ctx, c.span)
+	}
+	return ctx, c
+}
+
+// setTag sets a tag on the span of the call, if any
+func (c *call) setTag(key string, value interface{}) {
+	if c.span != nil {
+		c.span.SetTag(key, value)
+	}
+}
+
+// finish records the latency, logs the call if it is slow, tags the result and
+// finishes the span. rowsAffected is negative when it is not known.
+func (c *call) finish(ctx context.Context, rowsAffected int64, err error) {
+	duration := time.Since(c.start)
+	if c.metrics != nil {
+		c.timer(c.operation).Record(duration)
+	}
+	if c.logger != nil && c.slowQueryThreshold > 0 && duration >= c.slowQueryThreshold {
+		fields := []zap.Field{
+			zap.String("operation", c.operation),
+			zap.String("statement", c.sanitizer.Truncate(sanitizer.NormalizeSQL(c.statement))),
+			zap.Duration("duration", duration),
+		}
+		if rowsAffected >= 0 {
+			fields = append(fields, zap.Int64("rows-affected", rowsAffected))
+		}
+		c.logger.WithContext(ctx).Warn("Slow query", fields...)
+	}
+	if c.span != nil {
+		if rowsAffected >= 0 {
+			c.span.SetTag("db.rows_affected", rowsAffected)
+		}
+		if err != nil && !c.isIgnored(err) {
+			ext.Error.Set(c.span, true)
+			c.span.LogFields(
+				log.String("event", "error"),
+				log.String("error.kind", fmt.Sprintf("%T", err)),
+				log.String("message", err.Error()),
+			)
+		}
+		c.span.Finish()
+	}
+}
+
+func (i *instrumentation) isIgnored(err error) bool {
+	for _, ignored := range i.ignoredErrors {
+		if errors.Is(err, ignored) {
+			return true
+		}
+	}
+	return false
+}
+
+// rowsAffected returns the rows affected by result or -1 if it is not known
+func rowsAffected(result sql.Result) int64 {
+	if result == nil {
+		return -1
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return rows
+}
+
+func (i *instrumentation) timer(operation string) metrics.Timer {
+	if timer, ok := i.timers.Load(operation); ok {
+		return timer.(metrics.Timer)
+	}
+	timer, _ := i.timers.LoadOrStore(operation, i.metrics.Timer(metrics.TimerOptions{
+		Name: "latency",
+		Tags: map[string]string{"operation": operation},
+		Help: "Latency of SQL calls",
+	}))
+	return timer.(metrics.Timer)
+}
@@ -0,0 +1,218 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	datastore "github.com/dhyaniarun1993/foody-common/datastore/sql"
+)
+
+// migrate constants
+const (
+	Table       = "schema_migrations"
+	lockName    = "schema_migrations_lock"
+	lockTimeout = 60
+)
+
+// Status provides the state of a migration in the database
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrator applies versioned migrations to a database. Only one Migrator can
+// run at a time across replicas, the others wait on an advisory lock.
+type Migrator struct {
+	db         *datastore.DB
+	migrations []Migration
+}
+
+// New creates a Migrator with the migrations found in source, which can be
+// os.DirFS(dir) or an embed.FS(use fs.Sub to point it to the directory)
+func New(db *datastore.DB, source fs.FS) (*Migrator, error) {
+	migrations, err := load(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db, migrations}, nil
+}
+
+// Up applies all the pending migrations
+func (migrator *Migrator) Up(ctx context.Context) error {
+	return migrator.run(ctx, "migrate.up", func(ctx context.Context, conn *datastore.Conn, applied map[int64]bool) error {
+		for _, migration := range migrator.migrations {
+			if applied[migration.Version] {
+				continue
+			}
+			if err := migrator.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last applied migration
+func (migrator *Migrator) Down(ctx context.Context) error {
+	return migrator.run(ctx, "migrate.down", func(ctx context.Context, conn *datastore.Conn, applied map[int64]bool) error {
+		for i := len(migrator.migrations) - 1; i >= 0; i-- {
+			if applied[migrator.migrations[i].Version] {
+				return migrator.apply(ctx, conn, migrator.migrations[i], false)
+			}
+		}
+		return nil
+	})
+}
+
+// To migrates the database up or down so that version is the last applied
+// migration. Version 0 rolls back every migration.
+func (migrator *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && migrator.find(version) == nil {
+		return fmt.Errorf("migrate: unknown version %d", version)
+	}
+
+	return migrator.run(ctx, "migrate.to", func(ctx context.Context, conn *datastore.Conn, applied map[int64]bool) error {
+		for i := len(migrator.migrations) - 1; i >= 0; i-- {
+			migration := migrator.migrations[i]
+			if migration.Version > version && applied[migration.Version] {
+				if err := migrator.apply(ctx, conn, migration, false); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range migrator.migrations {
+			if migration.Version <= version && !applied[migration.Version] {
+				if err := migrator.apply(ctx, conn, migration, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns the state of every known migration ordered by version
+func (migrator *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := migrator.run(ctx, "migrate.status", func(ctx context.Context, conn *datastore.Conn, applied map[int64]bool) error {
+		var rows []struct {
+			Version   int64 `db:"version"`
+			AppliedAt int64 `db:"applied_at"`
+		}
+		query := fmt.Sprintf("SELECT version, UNIX_TIMESTAMP(applied_at) AS applied_at FROM %s", Table)
+		if err := conn.SelectContext(ctx, &rows, query); err != nil {
+			return err
+		}
+		appliedAt := map[int64]time.Time{}
+		for _, row := range rows {
+			appliedAt[row.Version] = time.Unix(row.AppliedAt, 0)
+		}
+
+		for _, migration := range migrator.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if at, ok := appliedAt[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (migrator *Migrator) find(version int64) *Migration {
+	for i := range migrator.migrations {
+		if migrator.migrations[i].Version == version {
+			return &migrator.migrations[i]
+		}
+	}
+	return nil
+}
+
+// run takes the advisory lock on a dedicated connection, makes sure the
+// version table exists and calls operation with the applied versions
+func (migrator *Migrator) run(ctx context.Context, operationName string,
+	operation func(ctx context.Context, conn *datastore.Conn, applied map[int64]bool) error) error {
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		span := parent.Tracer().StartSpan(operationName, opentracing.ChildOf(parent.Context()))
+		ctx = opentracing.ContextWithSpan(ctx, span)
+		defer span.Finish()
+	}
+
+	conn, err := migrator.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("migrate: could not acquire lock %q within %ds", lockName, lockTimeout)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+
+	createTable := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`, Table)
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+
+	var versions []int64
+	if err := conn.SelectContext(ctx, &versions, fmt.Sprintf("SELECT version FROM %s", Table)); err != nil {
+		return err
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+	return operation(ctx, conn, applied)
+}
+
+// apply runs the up or down script of migration and records the change in the
+// version table. DDL statements are not transactional in MySQL so a failed
+// migration has to be fixed by hand.
+func (migrator *Migrator) apply(ctx context.Context, conn *datastore.Conn, migration Migration, up bool) error {
+	script, operationName := migration.Up, "migrate.apply"
+	if !up {
+		script, operationName = migration.Down, "migrate.rollback"
+		if script == "" {
+			return fmt.Errorf("migrate: missing down migration for version %d", migration.Version)
+		}
+	}
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		span := parent.Tracer().StartSpan(operationName, opentracing.ChildOf(parent.Context()))
+		span.SetTag("migration.version", migration.Version)
+		span.SetTag("migration.name", migration.Name)
+		ctx = opentracing.ContextWithSpan(ctx, span)
+		defer span.Finish()
+	}
+
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migrate: version %d(%s) failed: %v", migration.Version, migration.Name, err)
+		}
+	}
+
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version, name) VALUES (?, ?)", Table),
+			migration.Version, migration.Name)
+	} else {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = ?", Table), migration.Version)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"only whitespace", " \n\t", nil},
+		{"single", "CREATE TABLE a (id INT);", []string{"CREATE TABLE a (id INT)"}},
+		{"trailing statement without semicolon", "SELECT 1;\nSELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"empty statements", ";;SELECT 1;;", []string{"SELECT 1"}},
+		{"semicolon in single quotes", "INSERT INTO a VALUES ('x;y');", []string{"INSERT INTO a VALUES ('x;y')"}},
+		{"semicolon in double quotes", `INSERT INTO a VALUES ("x;y");`, []string{`INSERT INTO a VALUES ("x;y")`}},
+		{"semicolon in backticks", "SELECT `a;b` FROM c;", []string{"SELECT `a;b` FROM c"}},
+		{"escaped quote", `INSERT INTO a VALUES ('it\'s;');SELECT 1`, []string{`INSERT INTO a VALUES ('it\'s;')`, "SELECT 1"}},
+		{"doubled quote", "INSERT INTO a VALUES ('it''s;');SELECT 1", []string{"INSERT INTO a VALUES ('it''s;')", "SELECT 1"}},
+		{"dashes in string", "INSERT INTO a VALUES ('-- x;');", []string{"INSERT INTO a VALUES ('-- x;')"}},
+		{"dash comment", "-- first; table\nSELECT 1;", []string{"-- first; table\nSELECT 1"}},
+		{"dash comment at the end", "SELECT 1; -- done;", []string{"SELECT 1"}},
+		{"dashes without space", "SELECT 2--1;", []string{"SELECT 2--1"}},
+		{"hash comment", "# a;b\nSELECT 1;", []string{"# a;b\nSELECT 1"}},
+		{"block comment", "/* a; b */ SELECT 1;", []string{"/* a; b */ SELECT 1"}},
+		{"multi line block comment", "SELECT /* a;\nb; */ 1;SELECT 2;", []string{"SELECT /* a;\nb; */ 1", "SELECT 2"}},
+		{"unterminated block comment", "SELECT 1; /* a; b", []string{"SELECT 1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := splitStatements(test.script); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", test.script, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsDashComment(t *testing.T) {
+	tests := []struct {
+		script string
+		want   bool
+	}{
+		{"--", true},
+		{"-- comment", true},
+		{"--\tcomment", true},
+		{"--\n", true},
+		{"--comment", false},
+		{"-1", false},
+		{"", false},
+		{"SELECT 1 -- comment", false},
+	}
+	for _, test := range tests {
+		if got := isDashComment(test.script); got != test.want {
+			t.Errorf("isDashComment(%q) = %v, want %v", test.script, got, test.want)
+		}
+	}
+}
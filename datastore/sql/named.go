@@ -0,0 +1,123 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// bindNamed replaces the :name parameters in query with ? bind variables and
+// returns the matching arguments taken from arg, a map with string keys or a
+// struct(or pointer to struct) with db tags. Quoted text and :: casts are left
+// untouched.
+func bindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	lookup, err := namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		bound strings.Builder
+		args  []interface{}
+		quote rune
+	)
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			bound.WriteRune(r)
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+			bound.WriteRune(r)
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			bound.WriteString("::")
+			i++
+		case r == ':' && i+1 < len(runes) && isNameRune(runes[i+1]):
+			j := i + 1
+			for j < len(runes) && isNameRune(runes[j]) {
+				j++
+			}
+			name := string(runes[i+1 : j])
+			value, ok := lookup(name)
+			if !ok {
+				return "", nil, fmt.Errorf("sql: could not find name %q in %T", name, arg)
+			}
+			args = append(args, value)
+			bound.WriteRune('?')
+			i = j - 1
+		default:
+			bound.WriteRune(r)
+		}
+	}
+	return bound.String(), args, nil
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// namedLookup returns a function that resolves parameter names against arg
+func namedLookup(arg interface{}) (func(name string) (interface{}, bool), error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		return func(name string) (interface{}, bool) {
+			value, ok := m[name]
+			return value, ok
+		}, nil
+	}
+
+	value := reflect.ValueOf(arg)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, fmt.Errorf("sql: named argument must not be nil")
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("sql: named argument map must have string keys, got %T", arg)
+		}
+		return func(name string) (interface{}, bool) {
+			v := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+			return v.Interface(), true
+		}, nil
+	case reflect.Struct:
+		fields := fieldMap(value.Type())
+		return func(name string) (interface{}, bool) {
+			index, ok := fields[strings.ToLower(name)]
+			if !ok {
+				return nil, false
+			}
+			field, ok := namedField(value, index)
+			if !ok {
+				return nil, false
+			}
+			return field.Interface(), true
+		}, nil
+	default:
+		return nil, fmt.Errorf("sql: named argument must be a map or struct, got %T", arg)
+	}
+}
+
+// namedField is fieldByIndex for read only values, nil embedded pointers are
+// reported as missing
+func namedField(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, fieldIndex := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(fieldIndex)
+	}
+	return value, true
+}
@@ -0,0 +1,151 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// replica selection policies
+const (
+	RoundRobin       = "round-robin"
+	LeastConnections = "least-connections"
+)
+
+const primaryNode = "primary"
+
+type primaryKey struct{}
+
+// WithPrimary returns a context that forces reads to be served by the primary.
+// It is useful to read your own writes right after they are made.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func isPrimaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// reader returns the node that should serve a read for the given context
+func (db *DB) reader(ctx context.Context) (string, *sql.DB) {
+	if db.replicas == nil || isPrimaryForced(ctx) {
+		return primaryNode, db.DB
+	}
+	if r := db.replicas.pick(); r != nil {
+		return r.name, r.DB
+	}
+	return primaryNode, db.DB
+}
+
+type replica struct {
+	*sql.DB
+	name    string
+	healthy int32
+}
+
+type replicaSet struct {
+	replicas []*replica
+	selector string
+	next     uint64
+	stop     chan struct{}
+}
+
+// newReplicaSet opens the replicas and pings them once, the replicas that do
+// not answer serve no read until the monitor reaches them
+func newReplicaSet(driver string, dsns []string, selector string, interval time.Duration) (*replicaSet, error) {
+	switch selector {
+	case "":
+		selector = RoundRobin
+	case RoundRobin, LeastConnections:
+	default:
+		return nil, fmt.Errorf("unknown replica selector %q, use %s or %s", selector, RoundRobin, LeastConnections)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("replica health check interval must be positive, got %s", interval)
+	}
+	set := &replicaSet{
+		selector: selector,
+		stop:     make(chan struct{}),
+	}
+	for i, dsn := range dsns {
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			set.close()
+			return nil, err
+		}
+		set.replicas = append(set.replicas, &replica{db, fmt.Sprintf("replica-%d", i), 0})
+	}
+	set.check(interval)
+	go set.monitor(interval)
+	return set, nil
+}
+
+// pick returns a healthy replica as per the selection policy or nil if there
+// is none
+func (set *replicaSet) pick() *replica {
+	switch set.selector {
+	case LeastConnections:
+		var selected *replica
+		for _, r := range set.replicas {
+			if atomic.LoadInt32(&r.healthy) == 0 {
+				continue
+			}
+			if selected == nil || r.Stats().InUse < selected.Stats().InUse {
+				selected = r
+			}
+		}
+		return selected
+	default:
+		count := uint64(len(set.replicas))
+		start := atomic.AddUint64(&set.next, 1)
+		for i := uint64(0); i < count; i++ {
+			r := set.replicas[(start+i)%count]
+			if atomic.LoadInt32(&r.healthy) == 1 {
+				return r
+			}
+		}
+		return nil
+	}
+}
+
+// monitor pings the replicas every interval and marks the failing ones
+// unhealthy so that reads are not routed to them
+func (set *replicaSet) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-set.stop:
+			return
+		case <-ticker.C:
+			set.check(interval)
+		}
+	}
+}
+
+// check pings every replica within timeout and records its health
+func (set *replicaSet) check(timeout time.Duration) {
+	for _, r := range set.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := r.PingContext(ctx); err != nil {
+			atomic.StoreInt32(&r.healthy, 0)
+		} else {
+			atomic.StoreInt32(&r.healthy, 1)
+		}
+		cancel()
+	}
+}
+
+func (set *replicaSet) close() error {
+	close(set.stop)
+	var closeErr error
+	for _, r := range set.replicas {
+		if err := r.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const tagName = "db"
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	fieldCache  sync.Map
+)
+
+// fieldMap returns the index path of every struct field keyed by column name.
+// Column name is taken from the db tag or the lower cased field name, fields
+// tagged with "-" are skipped and embedded structs are flattened.
+func fieldMap(structType reflect.Type) map[string][]int {
+	if fields, ok := fieldCache.Load(structType); ok {
+		return fields.(map[string][]int)
+	}
+
+	fields := map[string][]int{}
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get(tagName)
+			if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+				continue
+			}
+			path := append(append([]int{}, index...), i)
+			fieldType := field.Type
+			if field.Anonymous && tag == "" {
+				if fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if fieldType.Kind() == reflect.Struct {
+					walk(fieldType, path)
+					continue
+				}
+			}
+			name := strings.Split(tag, ",")[0]
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if _, exists := fields[name]; !exists {
+				fields[name] = path
+			}
+		}
+	}
+	walk(structType, nil)
+
+	fieldCache.Store(structType, fields)
+	return fields
+}
+
+// isScannable reports whether values of type t are scanned as a single column
+func isScannable(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(scannerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct
+}
+
+// fieldByIndex returns the field at index allocating nil embedded pointers
+func fieldByIndex(value reflect.Value, index []int) reflect.Value {
+	for i, fieldIndex := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(fieldIndex)
+	}
+	return value
+}
+
+// scanTargets returns the pointers that rows.Scan should write into
+func scanTargets(value reflect.Value, columns []string) ([]interface{}, error) {
+	if isScannable(value.Type()) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("sql: scannable destination %v needs exactly 1 column, got %d",
+				value.Type(), len(columns))
+		}
+		return []interface{}{value.Addr().Interface()}, nil
+	}
+
+	fields := fieldMap(value.Type())
+	targets := make([]interface{}, len(columns))
+	for i, column := range columns {
+		index, ok := fields[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("sql: missing destination field for column %q in %v",
+				column, value.Type())
+		}
+		targets[i] = fieldByIndex(value, index).Addr().Interface()
+	}
+	return targets, nil
+}
+
+// scanAll scans all the rows into dest which must be a pointer to a slice of
+// structs, pointers to structs or scannable values. rows is closed on return.
+func scanAll(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() || destValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("sql: destination must be a non nil pointer to slice, got %T", dest)
+	}
+	sliceValue := destValue.Elem()
+	elemType := sliceValue.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		targets, err := scanTargets(elem.Elem(), columns)
+		if err != nil {
+			return err
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		if isPtr {
+			sliceValue.Set(reflect.Append(sliceValue, elem))
+		} else {
+			sliceValue.Set(reflect.Append(sliceValue, elem.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// scanOne scans the first row into dest which must be a pointer to a struct
+// or a scannable value. sql.ErrNoRows is returned if there is no row. rows is
+// closed on return.
+func scanOne(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("sql: destination must be a non nil pointer, got %T", dest)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	targets, err := scanTargets(destValue.Elem(), columns)
+	if err != nil {
+		return err
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
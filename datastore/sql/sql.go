@@ -2,11 +2,14 @@ package sql
 
 import (
 	"database/sql"
+	"time"
 
 	// mysql driver
 	_ "github.com/go-sql-driver/mysql"
 
 	"github.com/opentracing/opentracing-go"
+
+	"github.com/dhyaniarun1993/foody-common/datastore/sanitizer"
 )
 
 // Configuration provides configuration for SQL Driver
@@ -15,14 +18,56 @@ type Configuration struct {
 	MaxIdleConnections    int    `required:"true" split_words:"true"`
 	MaxOpenConnections    int    `required:"true" split_words:"true"`
 	ConnectionMaxLifetime string `required:"true" split_words:"true"`
+
+	// Read replicas, reads are served by the primary if none is configured
+	ReplicaDSN                 []string `split_words:"true"`
+	ReplicaSelector            string   `split_words:"true" default:"round-robin" validate:"omitempty,oneof=round-robin least-connections"`
+	ReplicaHealthCheckInterval string   `split_words:"true" default:"5s"`
+
+	// Calls slower than the threshold are logged when a logger is provided
+	SlowQueryThreshold string `split_words:"true"`
+
+	// Sanitizer configures how statements are redacted on spans and logs
+	Sanitizer sanitizer.Configuration
 }
 
 // CreatePool creates connection pool for SQL server
-func CreatePool(configuration Configuration, driver string, tracer opentracing.Tracer) *DB {
+func CreatePool(configuration Configuration, driver string, tracer opentracing.Tracer, opts ...Option) *DB {
 	db, err := sql.Open(driver, configuration.DSN)
 	if err != nil {
 		panic(err)
 	}
 
-	return &DB{db, tracer}
+	instrument := &instrumentation{
+		tracer:        tracer,
+		sanitizer:     sanitizer.New(configuration.Sanitizer),
+		ignoredErrors: []error{sql.ErrNoRows},
+	}
+	if configuration.SlowQueryThreshold != "" {
+		instrument.slowQueryThreshold, err = time.ParseDuration(configuration.SlowQueryThreshold)
+		if err != nil {
+			panic(err)
+		}
+	}
+	for _, opt := range opts {
+		opt(instrument)
+	}
+
+	if len(configuration.ReplicaDSN) == 0 {
+		return &DB{DB: db, instrument: instrument}
+	}
+
+	interval := 5 * time.Second
+	if configuration.ReplicaHealthCheckInterval != "" {
+		interval, err = time.ParseDuration(configuration.ReplicaHealthCheckInterval)
+		if err != nil {
+			panic(err)
+		}
+	}
+	replicas, err := newReplicaSet(driver, configuration.ReplicaDSN,
+		configuration.ReplicaSelector, interval)
+	if err != nil {
+		panic(err)
+	}
+	return &DB{DB: db, instrument: instrument, replicas: replicas}
 }
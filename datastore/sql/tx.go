@@ -3,99 +3,80 @@ package sql
 import (
 	"context"
 	"database/sql"
-	"fmt"
-
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 )
 
 // Tx is a wrapper on sql.Tx with tracing Capability
 type Tx struct {
 	*sql.Tx
-	tracer opentracing.Tracer
+	instrument *instrumentation
 }
 
 // ExecContext instruments the sql.Tx ExecContext with tracing capability
 func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := tx.tracer.StartSpan(
-			fmt.Sprintf("txn.execute"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return tx.Tx.ExecContext(ctx, query, args...)
+	ctx, call := tx.instrument.startCall(ctx, "txn.execute", query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	call.finish(ctx, rowsAffected(result), err)
+	return result, err
 }
 
 // PrepareContext instruments the sql.Tx PrepareContext with tracing capability
 func (tx *Tx) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := tx.tracer.StartSpan(
-			fmt.Sprintf("txn.prepare"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
+	ctx, call := tx.instrument.startCall(ctx, "txn.prepare", query)
 	stmt, err := tx.Tx.PrepareContext(ctx, query)
-	return &Stmt{stmt, tx.tracer}, err
+	call.finish(ctx, -1, err)
+	return &Stmt{stmt, query, tx.instrument}, err
 }
 
 // QueryContext instruments the sql.Tx QueryContext with tracing capability
 func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := tx.tracer.StartSpan(
-			fmt.Sprintf("txn.query"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return tx.Tx.QueryContext(ctx, query, args...)
+	ctx, call := tx.instrument.startCall(ctx, "txn.query", query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	call.finish(ctx, -1, err)
+	return rows, err
 }
 
 // QueryRowContext instruments the sql.Tx QueryRowContext with tracing capability
 func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := tx.tracer.StartSpan(
-			fmt.Sprintf("txn.queryRow"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ext.DBStatement.Set(newSpan, query)
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
-	}
-	return tx.Tx.QueryRowContext(ctx, query, args...)
+	ctx, call := tx.instrument.startCall(ctx, "txn.queryRow", query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	call.finish(ctx, -1, row.Err())
+	return row
 }
 
 // StmtContext instruments the sql.Tx StmtContext with tracing capability
 func (tx *Tx) StmtContext(ctx context.Context, stmt *sql.Stmt) *sql.Stmt {
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
-		newSpan := tx.tracer.StartSpan(
-			fmt.Sprintf("txn.statement"),
-			opentracing.ChildOf(span.Context()),
-		)
-		ext.Component.Set(newSpan, "database/sql")
-		ext.SpanKind.Set(newSpan, "client")
-		ctx = opentracing.ContextWithSpan(ctx, newSpan)
-		defer newSpan.Finish()
+	ctx, call := tx.instrument.startCall(ctx, "txn.statement", "")
+	txStmt := tx.Tx.StmtContext(ctx, stmt)
+	call.finish(ctx, -1, nil)
+	return txStmt
+}
+
+// SelectContext runs the query and scans all the rows into dest, a pointer to
+// slice, mapping columns to struct fields by db tag
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanAll(rows, dest)
+}
+
+// GetContext runs the query and scans the first row into dest, mapping columns
+// to struct fields by db tag. sql.ErrNoRows is returned if there is no row.
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanOne(rows, dest)
+}
+
+// NamedExecContext executes the query binding :name parameters from arg, a
+// map with string keys or a struct with db tags
+func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	boundQuery, args, err := bindNamed(query, arg)
+	if err != nil {
+		return nil, err
 	}
-	return tx.Tx.StmtContext(ctx, stmt)
+	return tx.ExecContext(ctx, boundQuery, args...)
 }
@@ -12,6 +12,7 @@ type AppError interface {
 	Caller() string
 	ErrorStack() string
 	StatusCode() int
+	Retryable() bool
 }
 
 type appError struct {
@@ -19,12 +20,22 @@ type appError struct {
 	caller     string
 	message    string
 	statusCode int
+	retryable  bool
 }
 
 // NewAppError create an app error
 func NewAppError(message string, statusCode int, err error) AppError {
+	return newAppError(message, statusCode, err, false)
+}
+
+// NewRetryableAppError create an app error that is marked safe to retry
+func NewRetryableAppError(message string, statusCode int, err error) AppError {
+	return newAppError(message, statusCode, err, true)
+}
+
+func newAppError(message string, statusCode int, err error, retryable bool) *appError {
 	caller := "UNKNOWN CALLER"
-	pc, _, lineNumber, ok := runtime.Caller(1)
+	pc, _, lineNumber, ok := runtime.Caller(2)
 	details := runtime.FuncForPC(pc)
 	if ok && details != nil {
 		caller = details.Name() + ":" + strconv.Itoa(lineNumber)
@@ -35,6 +46,7 @@ func NewAppError(message string, statusCode int, err error) AppError {
 		caller:     caller,
 		message:    message,
 		statusCode: statusCode,
+		retryable:  retryable,
 	}
 }
 
@@ -50,6 +62,10 @@ func (err *appError) StatusCode() int {
 	return err.statusCode
 }
 
+func (err *appError) Retryable() bool {
+	return err.retryable
+}
+
 func (err *appError) ErrorStack() string {
 	errorStack := "ErrorStack :-\n"
 
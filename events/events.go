@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/dhyaniarun1993/foody-common/authentication"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/random"
+)
+
+// auth headers, same as the HTTP headers read by authentication.AuthHandler
+const (
+	UserIDHeader   = "X-User-ID"
+	UserRoleHeader = "X-User-Role"
+	ClientIDHeader = "X-Client-ID"
+)
+
+// Envelope wraps the payload of an event with the metadata needed to route,
+// version and trace it
+type Envelope struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Version   int               `json:"version"`
+	Timestamp time.Time         `json:"timestamp"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   json.RawMessage   `json:"payload"`
+}
+
+// NewEnvelope creates an Envelope with payload encoded as JSON
+func NewEnvelope(eventType string, version int, payload interface{}) (Envelope, errors.AppError) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, errors.NewAppError("Unable to encode event payload", http.StatusInternalServerError, err)
+	}
+	return Envelope{
+		ID:        random.ID(),
+		Type:      eventType,
+		Version:   version,
+		Timestamp: time.Now().UTC(),
+		Headers:   map[string]string{},
+		Payload:   data,
+	}, nil
+}
+
+// Decode decodes the payload into value
+func (envelope Envelope) Decode(value interface{}) errors.AppError {
+	if err := json.Unmarshal(envelope.Payload, value); err != nil {
+		return errors.NewAppError("Unable to decode "+envelope.Type+" payload", http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+// Handler handles an event
+type Handler func(ctx context.Context, envelope Envelope) errors.AppError
+
+// Middleware wraps a Handler
+type Middleware func(Handler) Handler
+
+// Chain wraps handler with middlewares, the first middleware is the outermost
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Publisher publishes events to a topic
+type Publisher interface {
+	Publish(ctx context.Context, topic string, envelope Envelope) errors.AppError
+}
+
+// Subscriber delivers the events of a topic to handler. Subscriptions with
+// the same group share the events, every group receives every event.
+type Subscriber interface {
+	Subscribe(ctx context.Context, topic string, group string, handler Handler) errors.AppError
+}
+
+// InjectHeaders returns a copy of envelope with the span context and the
+// Auth of ctx in its headers
+func InjectHeaders(ctx context.Context, tracer opentracing.Tracer, envelope Envelope) Envelope {
+	headers := map[string]string{}
+	for name, value := range envelope.Headers {
+		headers[name] = value
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		tracer.Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(headers))
+	}
+	if auth, ok := authentication.GetAuthFromContext(ctx); ok {
+		headers[UserIDHeader] = auth.GetUserID()
+		headers[UserRoleHeader] = auth.GetUserRole()
+		headers[ClientIDHeader] = auth.GetClientID()
+	}
+	envelope.Headers = headers
+	return envelope
+}
+
+// ExtractHeaders returns ctx with the Auth from the headers of envelope and
+// the span context of the producer, nil if the headers have none
+func ExtractHeaders(ctx context.Context, tracer opentracing.Tracer,
+	envelope Envelope) (context.Context, opentracing.SpanContext) {
+	userID := envelope.Headers[UserIDHeader]
+	userRole := envelope.Headers[UserRoleHeader]
+	clientID := envelope.Headers[ClientIDHeader]
+	if userID != "" && userRole != "" && clientID != "" {
+		ctx = authentication.ContextWithAuth(ctx, authentication.NewAuth(userID, userRole, clientID))
+	}
+	spanContext, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier(envelope.Headers))
+	if err != nil {
+		return ctx, nil
+	}
+	return ctx, spanContext
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+)
+
+type subscription struct {
+	ctx     context.Context
+	handler Handler
+}
+
+// MemoryBroker is an in-process Publisher and Subscriber meant for tests.
+// Events are delivered synchronously during Publish, to one subscription of
+// every group in round robin.
+type MemoryBroker struct {
+	mu        sync.Mutex
+	tracer    opentracing.Tracer
+	groups    map[string]map[string][]*subscription
+	next      map[string]int
+	published map[string][]Envelope
+}
+
+// NewMemoryBroker creates a MemoryBroker
+func NewMemoryBroker(tracer opentracing.Tracer) *MemoryBroker {
+	return &MemoryBroker{
+		tracer:    tracer,
+		groups:    map[string]map[string][]*subscription{},
+		next:      map[string]int{},
+		published: map[string][]Envelope{},
+	}
+}
+
+// Publish injects the headers of ctx in envelope and delivers it. Handler
+// errors are not returned to the publisher, like with a real broker.
+func (broker *MemoryBroker) Publish(ctx context.Context, topic string, envelope Envelope) errors.AppError {
+	envelope = InjectHeaders(ctx, broker.tracer, envelope)
+
+	broker.mu.Lock()
+	broker.published[topic] = append(broker.published[topic], envelope)
+	var targets []*subscription
+	for group, subscriptions := range broker.groups[topic] {
+		if len(subscriptions) == 0 {
+			continue
+		}
+		key := topic + "/" + group
+		targets = append(targets, subscriptions[broker.next[key]%len(subscriptions)])
+		broker.next[key]++
+	}
+	broker.mu.Unlock()
+
+	for _, target := range targets {
+		target.handler(target.ctx, envelope)
+	}
+	return nil
+}
+
+// Subscribe delivers the events of topic to handler until ctx is done
+func (broker *MemoryBroker) Subscribe(ctx context.Context, topic string, group string,
+	handler Handler) errors.AppError {
+	sub := &subscription{ctx: ctx, handler: handler}
+	broker.mu.Lock()
+	if broker.groups[topic] == nil {
+		broker.groups[topic] = map[string][]*subscription{}
+	}
+	broker.groups[topic][group] = append(broker.groups[topic][group], sub)
+	broker.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		subscriptions := broker.groups[topic][group]
+		for i, s := range subscriptions {
+			if s == sub {
+				broker.groups[topic][group] = append(subscriptions[:i:i], subscriptions[i+1:]...)
+				break
+			}
+		}
+	}()
+	return nil
+}
+
+// Published returns the events published to topic
+func (broker *MemoryBroker) Published(topic string) []Envelope {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	return append([]Envelope{}, broker.published[topic]...)
+}
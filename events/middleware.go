@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/logger"
+	"github.com/dhyaniarun1993/foody-common/retry"
+)
+
+// dead letter headers
+const (
+	DeadLetterReasonHeader = "X-Dead-Letter-Reason"
+	DeadLetterStatusHeader = "X-Dead-Letter-Status"
+)
+
+// Tracing handles every event in a consumer span that continues the trace of
+// the producer and adds the Auth of the producer to the context
+func Tracing(tracer opentracing.Tracer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, envelope Envelope) errors.AppError {
+			ctx, producer := ExtractHeaders(ctx, tracer, envelope)
+			spanOpts := []opentracing.StartSpanOption{ext.SpanKindConsumer}
+			if producer != nil {
+				spanOpts = append(spanOpts, opentracing.ChildOf(producer))
+			} else if parent := opentracing.SpanFromContext(ctx); parent != nil {
+				spanOpts = append(spanOpts, opentracing.ChildOf(parent.Context()))
+			}
+			span := tracer.StartSpan("Event."+envelope.Type, spanOpts...)
+			defer span.Finish()
+			span.SetTag("event.id", envelope.ID)
+			span.SetTag("event.version", envelope.Version)
+
+			err := next(opentracing.ContextWithSpan(ctx, span), envelope)
+			if err != nil {
+				ext.Error.Set(span, true)
+				span.LogFields(
+					log.String("event", "error"),
+					log.Int("status", err.StatusCode()),
+					log.String("message", err.Error()),
+				)
+			}
+			return err
+		}
+	}
+}
+
+// Logging logs every failed event with the trace and auth of the context,
+// use it after Tracing
+func Logging(logger *logger.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, envelope Envelope) errors.AppError {
+			start := time.Now()
+			err := next(ctx, envelope)
+			if err != nil {
+				logger.WithContext(ctx).WithError(err).Error("Unable to handle event",
+					zap.String("event-id", envelope.ID),
+					zap.String("event-type", envelope.Type),
+					zap.Int("event-version", envelope.Version),
+					zap.Duration("duration", time.Since(start)),
+				)
+			}
+			return err
+		}
+	}
+}
+
+// Recovery turns a panic of the handler into a 500 AppError
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, envelope Envelope) (err errors.AppError) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = errors.NewAppError(fmt.Sprintf("Event handler panicked: %v", r),
+						http.StatusInternalServerError, nil)
+				}
+			}()
+			return next(ctx, envelope)
+		}
+	}
+}
+
+// Retry calls the handler again on failure according to policy
+func Retry(policy retry.Policy) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, envelope Envelope) errors.AppError {
+			return retry.Do(ctx, policy, func() errors.AppError {
+				return next(ctx, envelope)
+			})
+		}
+	}
+}
+
+// DeadLetter publishes the events that failed to topic with the error in the
+// headers instead of failing. It should be the outermost middleware after
+// Tracing so that the retries are exhausted first.
+func DeadLetter(publisher Publisher, topic string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, envelope Envelope) errors.AppError {
+			err := next(ctx, envelope)
+			if err == nil {
+				return nil
+			}
+			deadLetter := envelope
+			deadLetter.Headers = map[string]string{}
+			for name, value := range envelope.Headers {
+				deadLetter.Headers[name] = value
+			}
+			deadLetter.Headers[DeadLetterReasonHeader] = err.Error()
+			deadLetter.Headers[DeadLetterStatusHeader] = strconv.Itoa(err.StatusCode())
+			if publishErr := publisher.Publish(ctx, topic, deadLetter); publishErr != nil {
+				return errors.NewAppError("Unable to publish event to dead letter topic "+topic,
+					http.StatusInternalServerError, publishErr)
+			}
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				span.LogFields(
+					log.String("event", "dead-letter"),
+					log.String("topic", topic),
+					log.String("message", err.Error()),
+				)
+			}
+			return nil
+		}
+	}
+}
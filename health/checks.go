@@ -0,0 +1,32 @@
+package health
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/dhyaniarun1993/foody-common/datastore/mongo"
+	"github.com/dhyaniarun1993/foody-common/datastore/sql"
+)
+
+// SQLCheck pings the primary of db
+func SQLCheck(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// MongoCheck pings the primary of client
+func MongoCheck(client *mongo.Client) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx, readpref.Primary())
+	}
+}
+
+// RedisCheck pings client
+func RedisCheck(client *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return client.WithContext(ctx).Ping().Err()
+	}
+}
@@ -0,0 +1,205 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// check statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check reports the health of a dependency, it returns nil when healthy
+type Check func(ctx context.Context) error
+
+// CheckOption configures a check
+type CheckOption func(*check)
+
+// WithTimeout sets how long the check may take, 2s by default
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(check *check) {
+		check.timeout = timeout
+	}
+}
+
+// NonCritical reports the check without failing readiness when it is down,
+// for dependencies the service can work without
+func NonCritical() CheckOption {
+	return func(check *check) {
+		check.critical = false
+	}
+}
+
+// Result is the status of a check
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	// Latency is the duration of the check in milliseconds
+	Latency   float64   `json:"latencyMs"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Report is the status of all the checks, it is down when a critical check
+// is down
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type check struct {
+	name     string
+	run      Check
+	timeout  time.Duration
+	critical bool
+
+	// mu is held while the check runs so concurrent probes share a result
+	mu     sync.Mutex
+	result *Result
+}
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithCacheTTL sets how long a result is reused, 5s by default
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(registry *Registry) {
+		registry.cacheTTL = ttl
+	}
+}
+
+// Registry runs the registered checks and serves the probes. Results are
+// cached so frequent probes do not load the dependencies.
+type Registry struct {
+	cacheTTL time.Duration
+	mu       sync.Mutex
+	checks   []*check
+}
+
+// NewRegistry creates a Registry
+func NewRegistry(opts ...Option) *Registry {
+	registry := &Registry{cacheTTL: 5 * time.Second}
+	for _, opt := range opts {
+		opt(registry)
+	}
+	return registry
+}
+
+// Register adds a critical check named name
+func (registry *Registry) Register(name string, run Check, opts ...CheckOption) {
+	check := &check{name: name, run: run, timeout: 2 * time.Second, critical: true}
+	for _, opt := range opts {
+		opt(check)
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.checks = append(registry.checks, check)
+}
+
+// Run runs the checks concurrently, or reuses their cached results
+func (registry *Registry) Run() Report {
+	registry.mu.Lock()
+	checks := append([]*check{}, registry.checks...)
+	registry.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, registered := range checks {
+		wg.Add(1)
+		go func(i int, registered *check) {
+			defer wg.Done()
+			results[i] = registered.status(registry.cacheTTL)
+		}(i, registered)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	report := Report{Status: StatusUp, Checks: results}
+	for _, result := range results {
+		if result.Critical && result.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// LiveHandler serves the liveness probe. It does not run the checks, a
+// failing dependency must not get the pod restarted.
+func (registry *Registry) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, Report{Status: StatusUp, Checks: []Result{}})
+	})
+}
+
+// ReadyHandler serves the readiness probe with the status of every check,
+// it responds 503 when a critical check is down
+func (registry *Registry) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, registry.Run())
+	})
+}
+
+// status returns the cached result of check or runs it
+func (check *check) status(cacheTTL time.Duration) Result {
+	check.mu.Lock()
+	defer check.mu.Unlock()
+	if check.result != nil && time.Since(check.result.CheckedAt) < cacheTTL {
+		return *check.result
+	}
+
+	// the result is shared, so a probe that gives up must not fail it
+	ctx, cancel := context.WithTimeout(context.Background(), check.timeout)
+	defer cancel()
+	start := time.Now()
+	err := call(ctx, check.run)
+	result := Result{
+		Name:      check.name,
+		Status:    StatusUp,
+		Critical:  check.critical,
+		Latency:   float64(time.Since(start)) / float64(time.Millisecond),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	check.result = &result
+	return result
+}
+
+// call runs check, it gives up when ctx is done even if check ignores ctx
+func call(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
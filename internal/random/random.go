@@ -0,0 +1,13 @@
+package random
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// ID returns a random 32 characters hex identifier
+func ID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,16 @@
+package timeutil
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep waits for duration or until ctx is done
+func Sleep(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
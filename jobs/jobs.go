@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/random"
+)
+
+// defaultMaxAttempts is used when MaxAttempts is not set
+const defaultMaxAttempts = 5
+
+// Job is a unit of background work
+type Job struct {
+	ID      string            `json:"id"`
+	Type    string            `json:"type"`
+	Payload json.RawMessage   `json:"payload"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// UniqueKey prevents enqueueing another job with the same key until this
+	// one completes or fails
+	UniqueKey string    `json:"uniqueKey,omitempty"`
+	RunAt     time.Time `json:"runAt"`
+	// Attempt is the current attempt, starting at 1
+	Attempt     int           `json:"attempt"`
+	MaxAttempts int           `json:"maxAttempts"`
+	LastBackoff time.Duration `json:"lastBackoff,omitempty"`
+	EnqueuedAt  time.Time     `json:"enqueuedAt"`
+}
+
+// Decode decodes the payload of the job into value
+func (job Job) Decode(value interface{}) errors.AppError {
+	if err := json.Unmarshal(job.Payload, value); err != nil {
+		return errors.NewAppError("Unable to decode "+job.Type+" job payload", http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+// Backend stores the jobs. Jobs are kept per type and reserved jobs become
+// visible again after the visibility timeout unless they are completed,
+// retried or failed before.
+type Backend interface {
+	// Enqueue adds job and returns its ID. If a job with the same UniqueKey
+	// is queued, the ID of that job is returned and job is not added.
+	Enqueue(ctx context.Context, job Job) (string, error)
+	// Reserve returns a due job of one of jobTypes with Attempt incremented,
+	// nil if there is none
+	Reserve(ctx context.Context, jobTypes []string, visibility time.Duration) (*Job, error)
+	// Complete removes the job
+	Complete(ctx context.Context, job Job) error
+	// Retry schedules the job again at job.RunAt
+	Retry(ctx context.Context, job Job) error
+	// Fail removes the job and keeps it as dead
+	Fail(ctx context.Context, job Job, reason string) error
+}
+
+// EnqueueOption configures a job when it is enqueued
+type EnqueueOption func(*Job)
+
+// At runs the job at runAt
+func At(runAt time.Time) EnqueueOption {
+	return func(job *Job) {
+		job.RunAt = runAt
+	}
+}
+
+// After runs the job after delay
+func After(delay time.Duration) EnqueueOption {
+	return func(job *Job) {
+		job.RunAt = time.Now().Add(delay)
+	}
+}
+
+// Unique skips the job if a job with key is already queued
+func Unique(key string) EnqueueOption {
+	return func(job *Job) {
+		job.UniqueKey = key
+	}
+}
+
+// MaxAttempts sets how many times the job is tried before it fails, 5 by
+// default
+func MaxAttempts(maxAttempts int) EnqueueOption {
+	return func(job *Job) {
+		job.MaxAttempts = maxAttempts
+	}
+}
+
+// Queue enqueues jobs in a Backend
+type Queue struct {
+	backend Backend
+	tracer  opentracing.Tracer
+}
+
+// New creates a Queue
+func New(backend Backend, tracer opentracing.Tracer) *Queue {
+	return &Queue{backend, tracer}
+}
+
+// Enqueue adds a job of jobType with payload encoded as JSON and returns the
+// job ID. The job runs now unless At or After is given. The span context of
+// ctx is saved with the job.
+func (queue *Queue) Enqueue(ctx context.Context, jobType string, payload interface{},
+	opts ...EnqueueOption) (string, errors.AppError) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.NewAppError("Unable to encode "+jobType+" job payload", http.StatusInternalServerError, err)
+	}
+	now := time.Now()
+	job := Job{
+		ID:          random.ID(),
+		Type:        jobType,
+		Payload:     data,
+		Headers:     map[string]string{},
+		RunAt:       now,
+		MaxAttempts: defaultMaxAttempts,
+		EnqueuedAt:  now,
+	}
+	for _, opt := range opts {
+		opt(&job)
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		queue.tracer.Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(job.Headers))
+	}
+
+	id, err := queue.backend.Enqueue(ctx, job)
+	if err != nil {
+		return "", errors.NewRetryableAppError("Unable to enqueue "+jobType+" job", http.StatusServiceUnavailable, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeadJob is a job that failed for good
+type DeadJob struct {
+	Job    Job
+	Reason string
+}
+
+type memoryEntry struct {
+	job          Job
+	reservedTill time.Time
+}
+
+// MemoryBackend keeps the jobs in memory, it is meant for tests
+type MemoryBackend struct {
+	mu     sync.Mutex
+	jobs   map[string]*memoryEntry
+	unique map[string]string
+	dead   []DeadJob
+}
+
+// NewMemoryBackend creates a MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{jobs: map[string]*memoryEntry{}, unique: map[string]string{}}
+}
+
+// Enqueue adds job
+func (backend *MemoryBackend) Enqueue(ctx context.Context, job Job) (string, error) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if job.UniqueKey != "" {
+		if id, ok := backend.unique[job.UniqueKey]; ok {
+			return id, nil
+		}
+		backend.unique[job.UniqueKey] = job.ID
+	}
+	backend.jobs[job.ID] = &memoryEntry{job: job}
+	return job.ID, nil
+}
+
+// Reserve returns the due job of jobTypes that should have run first
+func (backend *MemoryBackend) Reserve(ctx context.Context, jobTypes []string, visibility time.Duration) (*Job, error) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	types := map[string]bool{}
+	for _, jobType := range jobTypes {
+		types[jobType] = true
+	}
+
+	now := time.Now()
+	var due []*memoryEntry
+	for _, entry := range backend.jobs {
+		if types[entry.job.Type] && !entry.job.RunAt.After(now) && !entry.reservedTill.After(now) {
+			due = append(due, entry)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].job.RunAt.Before(due[j].job.RunAt) })
+	entry := due[0]
+	entry.job.Attempt++
+	entry.reservedTill = now.Add(visibility)
+	job := entry.job
+	return &job, nil
+}
+
+// Complete removes job
+func (backend *MemoryBackend) Complete(ctx context.Context, job Job) error {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	backend.remove(job)
+	return nil
+}
+
+// Retry schedules job again at job.RunAt
+func (backend *MemoryBackend) Retry(ctx context.Context, job Job) error {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if _, ok := backend.jobs[job.ID]; ok {
+		backend.jobs[job.ID] = &memoryEntry{job: job}
+	}
+	return nil
+}
+
+// Fail removes job and keeps it in the dead jobs
+func (backend *MemoryBackend) Fail(ctx context.Context, job Job, reason string) error {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	backend.remove(job)
+	backend.dead = append(backend.dead, DeadJob{job, reason})
+	return nil
+}
+
+// Pending returns the jobs that are not completed or failed
+func (backend *MemoryBackend) Pending() []Job {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	jobs := make([]Job, 0, len(backend.jobs))
+	for _, entry := range backend.jobs {
+		jobs = append(jobs, entry.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].RunAt.Before(jobs[j].RunAt) })
+	return jobs
+}
+
+// Dead returns the failed jobs
+func (backend *MemoryBackend) Dead() []DeadJob {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	return append([]DeadJob{}, backend.dead...)
+}
+
+func (backend *MemoryBackend) remove(job Job) {
+	delete(backend.jobs, job.ID)
+	if job.UniqueKey != "" && backend.unique[job.UniqueKey] == job.ID {
+		delete(backend.unique, job.UniqueKey)
+	}
+}
@@ -0,0 +1,189 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// deadJobsLimit is the number of dead jobs kept by RedisBackend
+const deadJobsLimit = 1000
+
+// enqueueScript adds a job unless its unique key is taken, it returns the id
+// of the job holding the unique key
+var enqueueScript = redis.NewScript(`
+if KEYS[3] then
+	local existing = redis.call('GET', KEYS[3])
+	if existing then
+		return existing
+	end
+	redis.call('SET', KEYS[3], ARGV[1])
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return ARGV[1]
+`)
+
+// reserveScript makes the expired reservations visible again, moves the due
+// job that should have run first to the reserved set and increments its
+// attempt
+var reserveScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('ZADD', KEYS[1], ARGV[1], id)
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+redis.call('ZREM', KEYS[1], ids[1])
+local job = redis.call('HGET', KEYS[3], ids[1])
+if not job then
+	return false
+end
+redis.call('ZADD', KEYS[2], ARGV[2], ids[1])
+local attempt = redis.call('HINCRBY', KEYS[4], ids[1], 1)
+return {job, attempt}
+`)
+
+// releaseScript removes a job and its unique key if the key still belongs
+// to the job
+var releaseScript = redis.NewScript(`
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+if KEYS[4] and redis.call('GET', KEYS[4]) == ARGV[1] then
+	redis.call('DEL', KEYS[4])
+end
+return 1
+`)
+
+// RedisBackend stores the jobs in Redis. Every job type has a sorted set of
+// scheduled jobs by run time and a sorted set of reserved jobs by visibility
+// deadline, the jobs themselves are stored as JSON in a hash and their
+// attempts are counted in another hash by the reserve script. The keys share
+// the hash tag {prefix} and the scripts only use the keys they are given, so
+// they stay in one slot on Redis Cluster.
+type RedisBackend struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisBackend creates a RedisBackend with keys starting with {prefix}
+func NewRedisBackend(client *redis.Client, prefix string) *RedisBackend {
+	return &RedisBackend{client, "{" + prefix + "}"}
+}
+
+// Enqueue adds job, the unique key check and the insert are atomic
+func (backend *RedisBackend) Enqueue(ctx context.Context, job Job) (string, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	keys := []string{backend.jobsKey(), backend.scheduledKey(job.Type)}
+	if job.UniqueKey != "" {
+		keys = append(keys, backend.uniqueKey(job.UniqueKey))
+	}
+	return enqueueScript.Run(backend.client.WithContext(ctx), keys, job.ID, data, score(job.RunAt)).String()
+}
+
+// Reserve returns a due job of jobTypes
+func (backend *RedisBackend) Reserve(ctx context.Context, jobTypes []string, visibility time.Duration) (*Job, error) {
+	client := backend.client.WithContext(ctx)
+	now := time.Now()
+	for _, jobType := range jobTypes {
+		result, err := reserveScript.Run(client,
+			[]string{backend.scheduledKey(jobType), backend.reservedKey(jobType), backend.jobsKey(),
+				backend.attemptsKey()},
+			score(now), score(now.Add(visibility))).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		reserved := result.([]interface{})
+		var job Job
+		if err := json.Unmarshal([]byte(reserved[0].(string)), &job); err != nil {
+			return nil, err
+		}
+		job.Attempt = int(reserved[1].(int64))
+		return &job, nil
+	}
+	return nil, nil
+}
+
+// Complete removes job
+func (backend *RedisBackend) Complete(ctx context.Context, job Job) error {
+	return backend.release(ctx, job)
+}
+
+// Retry schedules job again at job.RunAt
+func (backend *RedisBackend) Retry(ctx context.Context, job Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	pipe := backend.client.WithContext(ctx).TxPipeline()
+	pipe.HSet(backend.jobsKey(), job.ID, data)
+	pipe.ZRem(backend.reservedKey(job.Type), job.ID)
+	pipe.ZAdd(backend.scheduledKey(job.Type), redis.Z{Score: score(job.RunAt), Member: job.ID})
+	_, err = pipe.Exec()
+	return err
+}
+
+// Fail removes job and keeps it in the list of the last dead jobs
+func (backend *RedisBackend) Fail(ctx context.Context, job Job, reason string) error {
+	data, err := json.Marshal(DeadJob{job, reason})
+	if err != nil {
+		return err
+	}
+	pipe := backend.client.WithContext(ctx).TxPipeline()
+	pipe.LPush(backend.deadKey(), data)
+	pipe.LTrim(backend.deadKey(), 0, deadJobsLimit-1)
+	if _, err := pipe.Exec(); err != nil {
+		return err
+	}
+	return backend.release(ctx, job)
+}
+
+func (backend *RedisBackend) release(ctx context.Context, job Job) error {
+	keys := []string{backend.reservedKey(job.Type), backend.jobsKey(), backend.attemptsKey()}
+	if job.UniqueKey != "" {
+		keys = append(keys, backend.uniqueKey(job.UniqueKey))
+	}
+	return releaseScript.Run(backend.client.WithContext(ctx), keys, job.ID).Err()
+}
+
+func (backend *RedisBackend) jobsKey() string {
+	return backend.prefix + ":jobs"
+}
+
+func (backend *RedisBackend) attemptsKey() string {
+	return backend.prefix + ":attempts"
+}
+
+func (backend *RedisBackend) deadKey() string {
+	return backend.prefix + ":dead"
+}
+
+func (backend *RedisBackend) scheduledKey(jobType string) string {
+	return backend.prefix + ":scheduled:" + jobType
+}
+
+func (backend *RedisBackend) reservedKey(jobType string) string {
+	return backend.prefix + ":reserved:" + jobType
+}
+
+func (backend *RedisBackend) uniqueKey(key string) string {
+	return backend.prefix + ":unique:" + key
+}
+
+// score is the sorted set score of t, in milliseconds
+func score(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Millisecond))
+}
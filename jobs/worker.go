@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/async"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/timeutil"
+	"github.com/dhyaniarun1993/foody-common/logger"
+	"github.com/dhyaniarun1993/foody-common/retry"
+)
+
+// Handler handles a job, the job is retried when an error is returned
+type Handler func(ctx context.Context, job Job) errors.AppError
+
+// WorkerOptions configures a Worker, zero values use the defaults
+type WorkerOptions struct {
+	// Concurrency is the number of jobs handled at once, 1 by default
+	Concurrency int
+	// Visibility is how long a reserved job is hidden from the other workers,
+	// 30s by default. It must be longer than the handler takes or jobs are
+	// handled twice.
+	Visibility time.Duration
+	// PollInterval is the wait when no job is due, 1s by default
+	PollInterval time.Duration
+	// Backoff is the wait before a failed job is retried, decorrelated jitter
+	// from 1s to 10m by default
+	Backoff retry.Backoff
+
+	// Logger logs the failed jobs when set
+	Logger *logger.Logger
+}
+
+// Worker reserves the due jobs of the registered types and handles them,
+// failed jobs are retried with backoff until they run out of attempts
+type Worker struct {
+	queue    *Queue
+	options  WorkerOptions
+	mu       sync.Mutex
+	handlers map[string]Handler
+}
+
+// NewWorker creates a Worker for the jobs of queue
+func NewWorker(queue *Queue, options WorkerOptions) *Worker {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	if options.Visibility <= 0 {
+		options.Visibility = 30 * time.Second
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	if options.Backoff == nil {
+		options.Backoff = retry.DecorrelatedJitterBackoff(time.Second, 10*time.Minute)
+	}
+	return &Worker{queue: queue, options: options, handlers: map[string]Handler{}}
+}
+
+// Register sets the handler of jobType, it must be called before Run
+func (worker *Worker) Register(jobType string, handler Handler) {
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+	worker.handlers[jobType] = handler
+}
+
+// Run handles jobs until ctx is cancelled, it waits for the running jobs
+// before returning
+func (worker *Worker) Run(ctx context.Context) errors.AppError {
+	worker.mu.Lock()
+	jobTypes := make([]string, 0, len(worker.handlers))
+	for jobType := range worker.handlers {
+		jobTypes = append(jobTypes, jobType)
+	}
+	worker.mu.Unlock()
+	if len(jobTypes) == 0 {
+		return errors.NewAppError("No job handler registered", http.StatusInternalServerError, nil)
+	}
+	sort.Strings(jobTypes)
+
+	group, _ := async.WithContext(ctx)
+	for i := 0; i < worker.options.Concurrency; i++ {
+		group.Go(func() errors.AppError {
+			worker.poll(ctx, jobTypes)
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+// poll reserves and handles jobs until ctx is cancelled
+func (worker *Worker) poll(ctx context.Context, jobTypes []string) {
+	for ctx.Err() == nil {
+		job, err := worker.queue.backend.Reserve(ctx, jobTypes, worker.options.Visibility)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			worker.logError(ctx, err, "Unable to reserve job")
+			timeutil.Sleep(ctx, worker.options.PollInterval)
+			continue
+		}
+		if job == nil {
+			timeutil.Sleep(ctx, worker.options.PollInterval)
+			continue
+		}
+		worker.process(ctx, *job)
+	}
+}
+
+// process handles job in a span that follows the span that enqueued it and
+// completes, retries or fails the job
+func (worker *Worker) process(ctx context.Context, job Job) {
+	var spanOpts []opentracing.StartSpanOption
+	if producer, err := worker.queue.tracer.Extract(opentracing.TextMap,
+		opentracing.TextMapCarrier(job.Headers)); err == nil {
+		spanOpts = append(spanOpts, opentracing.FollowsFrom(producer))
+	}
+	span := worker.queue.tracer.StartSpan("Job."+job.Type, spanOpts...)
+	defer span.Finish()
+	ext.SpanKind.Set(span, ext.SpanKindConsumerEnum)
+	ext.Component.Set(span, "jobs")
+	span.SetTag("job.id", job.ID)
+	span.SetTag("job.attempt", job.Attempt)
+	// the job is completed, retried or failed even when ctx is cancelled
+	// while it is handled
+	spanCtx := opentracing.ContextWithSpan(context.Background(), span)
+
+	err := worker.handle(opentracing.ContextWithSpan(ctx, span), job)
+	if err == nil {
+		if err := worker.queue.backend.Complete(spanCtx, job); err != nil {
+			worker.logError(spanCtx, err, "Unable to complete job", zap.String("job", job.ID))
+		}
+		return
+	}
+
+	ext.Error.Set(span, true)
+	span.LogFields(
+		log.String("event", "error"),
+		log.Int("status", err.StatusCode()),
+		log.String("message", err.Error()),
+	)
+	fields := []zap.Field{zap.String("job", job.ID), zap.String("type", job.Type), zap.Int("attempt", job.Attempt)}
+	if job.Attempt >= job.MaxAttempts {
+		worker.logError(spanCtx, err, "Job failed, giving up", fields...)
+		if err := worker.queue.backend.Fail(spanCtx, job, err.Error()); err != nil {
+			worker.logError(spanCtx, err, "Unable to fail job", fields...)
+		}
+		return
+	}
+
+	job.LastBackoff = worker.options.Backoff.Next(job.Attempt, job.LastBackoff)
+	job.RunAt = time.Now().Add(job.LastBackoff)
+	worker.logError(spanCtx, err, "Job failed, retrying", append(fields, zap.Duration("wait", job.LastBackoff))...)
+	if err := worker.queue.backend.Retry(spanCtx, job); err != nil {
+		worker.logError(spanCtx, err, "Unable to retry job", fields...)
+	}
+}
+
+// handle calls the handler of job and turns a panic into an error
+func (worker *Worker) handle(ctx context.Context, job Job) (err errors.AppError) {
+	worker.mu.Lock()
+	handler := worker.handlers[job.Type]
+	worker.mu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.NewAppError(fmt.Sprintf("Job handler panicked: %v", r), http.StatusInternalServerError, nil)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+func (worker *Worker) logError(ctx context.Context, err error, message string, fields ...zap.Field) {
+	if worker.options.Logger != nil {
+		worker.options.Logger.WithContext(ctx).WithError(err).Error(message, fields...)
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"strconv"
 
@@ -16,13 +17,14 @@ import (
 
 // Configuration provides configuration for zap logger
 type Configuration struct {
-	Level  string `required:"true" split_words:"true"`
+	Level  string `required:"true" split_words:"true" validate:"oneof=DEBUG INFO WARN ERROR"`
 	Format string `required:"true" split_words:"true"`
 }
 
 // Logger provides structure logging backed by uber zap logger
 type Logger struct {
 	*zap.Logger
+	level zap.AtomicLevel
 }
 
 func customCallerEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
@@ -36,22 +38,14 @@ func customCallerEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayE
 
 // CreateLogger creates logrus logger
 func CreateLogger(configuration Configuration) *Logger {
-	var logLevel zapcore.Level
-	switch configuration.Level {
-	case "DEBUG":
-		logLevel = zapcore.DebugLevel
-	case "INFO":
-		logLevel = zapcore.InfoLevel
-	case "WARN":
-		logLevel = zapcore.WarnLevel
-	case "ERROR":
-		logLevel = zapcore.ErrorLevel
-	default:
+	logLevel, ok := parseLevel(configuration.Level)
+	if !ok {
 		panic("Invalid Log Level")
 	}
+	level := zap.NewAtomicLevelAt(logLevel)
 	cfg := zap.Config{
 		Encoding:         configuration.Format,
-		Level:            zap.NewAtomicLevelAt(logLevel),
+		Level:            level,
 		OutputPaths:      []string{"stderr"},
 		ErrorOutputPaths: []string{"stderr"},
 		EncoderConfig: zapcore.EncoderConfig{
@@ -70,7 +64,35 @@ func CreateLogger(configuration Configuration) *Logger {
 		},
 	}
 	logger, _ := cfg.Build()
-	return &Logger{logger}
+	return &Logger{logger, level}
+}
+
+func parseLevel(level string) (zapcore.Level, bool) {
+	switch level {
+	case "DEBUG":
+		return zapcore.DebugLevel, true
+	case "INFO":
+		return zapcore.InfoLevel, true
+	case "WARN":
+		return zapcore.WarnLevel, true
+	case "ERROR":
+		return zapcore.ErrorLevel, true
+	}
+	return zapcore.InfoLevel, false
+}
+
+// SetLevel changes the level of the logger and of all the loggers derived
+// from it, e.g. when the configuration is reloaded
+func (logger *Logger) SetLevel(level string) error {
+	logLevel, ok := parseLevel(level)
+	if !ok {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	if logger.level == (zap.AtomicLevel{}) {
+		return fmt.Errorf("logger was not created by CreateLogger")
+	}
+	logger.level.SetLevel(logLevel)
+	return nil
 }
 
 // WithContext return a new Logger with as much context as possible
@@ -97,7 +119,7 @@ func (logger *Logger) WithContext(ctx context.Context) *Logger {
 	}
 
 	if newLogger != nil {
-		return &Logger{newLogger}
+		return &Logger{newLogger, logger.level}
 	}
 	return logger
 }
@@ -115,5 +137,5 @@ func (logger *Logger) WithError(err error) *Logger {
 	} else {
 		newLogger = logger.With(zap.String("error", err.Error()))
 	}
-	return &Logger{newLogger}
+	return &Logger{newLogger, logger.level}
 }
@@ -0,0 +1,188 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dhyaniarun1993/foody-common/authentication"
+)
+
+// idempotency headers
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyOptions configures the Idempotency middleware, zero values use
+// the defaults
+type IdempotencyOptions struct {
+	// TTL is how long a response is replayed, 24h by default
+	TTL time.Duration
+	// LockTTL is how long a key stays reserved by a request in flight, it
+	// must be longer than the request takes. 1m by default.
+	LockTTL time.Duration
+	// MaxBodySize is the largest request body in bytes read to hash the
+	// request, larger bodies get 413. 1MB by default.
+	MaxBodySize int64
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests with an
+// Idempotency-Key header safe to retry. The key is scoped to the user and
+// client of the request Auth, so it must run after AuthHandler; requests with
+// a key and no Auth get 401. The response of the first request is stored
+// and replayed for the repeats, a repeat sent while the first request is in
+// flight gets 409 and a repeat with a different body gets 422. Server errors
+// are not stored so the request can be retried.
+func Idempotency(store IdempotencyStore, options IdempotencyOptions) mux.MiddlewareFunc {
+	if options.TTL <= 0 {
+		options.TTL = 24 * time.Hour
+	}
+	if options.LockTTL <= 0 {
+		options.LockTTL = time.Minute
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = 1 << 20
+	}
+	return func(next http.Handler) http.Handler {
+		handlerFunc := func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" || !isUnsafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			auth, ok := authentication.GetAuthFromContext(r.Context())
+			if !ok {
+				writeJSONMessage(w, http.StatusUnauthorized, "Auth info missing.")
+				return
+			}
+
+			body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, options.MaxBodySize))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					writeJSONMessage(w, http.StatusRequestEntityTooLarge, "Request body too large.")
+					return
+				}
+				writeJSONMessage(w, http.StatusBadRequest, "Unable to read request body.")
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			key := idempotencyStoreKey(auth, idempotencyKey)
+			requestHash := hashRequest(r, body)
+			record, err := store.Start(r.Context(), key, requestHash, options.LockTTL)
+			if err != nil {
+				writeJSONMessage(w, http.StatusServiceUnavailable, "Unable to check idempotency key.")
+				return
+			}
+			if record != nil {
+				switch {
+				case record.RequestHash != requestHash:
+					writeJSONMessage(w, http.StatusUnprocessableEntity,
+						"Idempotency key was already used with a different request.")
+				case !record.Completed:
+					writeJSONMessage(w, http.StatusConflict, "A request with this idempotency key is in progress.")
+				default:
+					replay(w, record)
+				}
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			// the key is released or the response saved even when the request
+			// context is done
+			completed := false
+			defer func() {
+				if !completed {
+					store.Abort(context.Background(), key)
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+			if recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			err = store.Complete(context.Background(), key, IdempotencyRecord{
+				RequestHash: requestHash,
+				Completed:   true,
+				Status:      recorder.status,
+				Header:      recorder.header,
+				Body:        recorder.body.Bytes(),
+			}, options.TTL)
+			completed = err == nil
+		}
+		return http.HandlerFunc(handlerFunc)
+	}
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// idempotencyStoreKey scopes idempotencyKey to the user and client of auth
+func idempotencyStoreKey(auth authentication.Auth, idempotencyKey string) string {
+	return fmt.Sprintf("%s:%s:%s", auth.GetUserID(), auth.GetClientID(), idempotencyKey)
+}
+
+// hashRequest identifies a request by its method, path and body
+func hashRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.Path)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, record *IdempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+func writeJSONMessage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"message": %q}`, message)
+}
+
+// responseRecorder writes the response and keeps a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if recorder.wroteHeader {
+		return
+	}
+	recorder.wroteHeader = true
+	recorder.status = status
+	recorder.header = recorder.ResponseWriter.Header().Clone()
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	if !recorder.wroteHeader {
+		recorder.WriteHeader(http.StatusOK)
+	}
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// IdempotencyRecord is the state of an idempotency key
+type IdempotencyRecord struct {
+	// RequestHash identifies the request that used the key first
+	RequestHash string `json:"requestHash"`
+	// Completed is false while the first request is in flight
+	Completed bool        `json:"completed"`
+	Status    int         `json:"status,omitempty"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+}
+
+// IdempotencyStore keeps the responses of idempotent requests
+type IdempotencyStore interface {
+	// Start reserves key for the request with requestHash for lockTTL. It
+	// returns the existing record if key is already used and nil if it was
+	// reserved.
+	Start(ctx context.Context, key string, requestHash string, lockTTL time.Duration) (*IdempotencyRecord, error)
+	// Complete saves the response of key for ttl
+	Complete(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	// Abort releases key so the request can be sent again
+	Abort(ctx context.Context, key string) error
+}
+
+// RedisIdempotencyStore keeps the records in Redis as JSON
+type RedisIdempotencyStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisIdempotencyStore creates a RedisIdempotencyStore with keys starting
+// with prefix
+func NewRedisIdempotencyStore(client *redis.Client, prefix string) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client, prefix}
+}
+
+// Start reserves key with SETNX
+func (store *RedisIdempotencyStore) Start(ctx context.Context, key string, requestHash string,
+	lockTTL time.Duration) (*IdempotencyRecord, error) {
+	data, err := json.Marshal(IdempotencyRecord{RequestHash: requestHash})
+	if err != nil {
+		return nil, err
+	}
+	client := store.client.WithContext(ctx)
+	reserved, err := client.SetNX(store.prefix+key, data, lockTTL).Result()
+	if err != nil || reserved {
+		return nil, err
+	}
+
+	data, err = client.Get(store.prefix + key).Bytes()
+	if err == redis.Nil {
+		// the key expired in between, reserve it again
+		return store.Start(ctx, key, requestHash, lockTTL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record IdempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete saves record for ttl
+func (store *RedisIdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord,
+	ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return store.client.WithContext(ctx).Set(store.prefix+key, data, ttl).Err()
+}
+
+// Abort deletes key
+func (store *RedisIdempotencyStore) Abort(ctx context.Context, key string) error {
+	return store.client.WithContext(ctx).Del(store.prefix + key).Err()
+}
+
+type memoryIdempotencyEntry struct {
+	record    IdempotencyRecord
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore keeps the records in memory, it is meant for tests
+// and single instance services
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]memoryIdempotencyEntry
+}
+
+// NewMemoryIdempotencyStore creates a MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: map[string]memoryIdempotencyEntry{}}
+}
+
+// Start reserves key unless it is used and not expired
+func (store *MemoryIdempotencyStore) Start(ctx context.Context, key string, requestHash string,
+	lockTTL time.Duration) (*IdempotencyRecord, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := time.Now()
+	if entry, ok := store.records[key]; ok && now.Before(entry.expiresAt) {
+		record := entry.record
+		return &record, nil
+	}
+	store.records[key] = memoryIdempotencyEntry{IdempotencyRecord{RequestHash: requestHash}, now.Add(lockTTL)}
+	return nil, nil
+}
+
+// Complete saves record for ttl
+func (store *MemoryIdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord,
+	ttl time.Duration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.records[key] = memoryIdempotencyEntry{record, time.Now().Add(ttl)}
+	return nil
+}
+
+// Abort deletes key
+func (store *MemoryIdempotencyStore) Abort(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.records, key)
+	return nil
+}
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RateLimitConfiguration provides configuration for RateLimiter
+type RateLimitConfiguration struct {
+	// Rate is the number of requests allowed per second
+	Rate float64 `required:"true" validate:"gt=0"`
+	// Burst is the number of requests allowed at once
+	Burst int `required:"true" validate:"gte=1"`
+}
+
+// RateLimiter is a token bucket limiting the requests served by an instance,
+// its limits can be changed while it is in use
+type RateLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with a full bucket
+func NewRateLimiter(configuration RateLimitConfiguration) *RateLimiter {
+	limiter := &RateLimiter{tokens: float64(configuration.Burst), lastFill: time.Now()}
+	limiter.SetLimit(configuration)
+	return limiter
+}
+
+// SetLimit swaps the limits, the tokens left are kept up to the new burst
+func (limiter *RateLimiter) SetLimit(configuration RateLimitConfiguration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.fill(time.Now())
+	limiter.rate = configuration.Rate
+	limiter.burst = float64(configuration.Burst)
+	limiter.tokens = math.Min(limiter.tokens, limiter.burst)
+}
+
+// Allow takes a token, it returns false and the wait until the next token
+// when there is none
+func (limiter *RateLimiter) Allow() (bool, time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.fill(time.Now())
+	if limiter.tokens >= 1 {
+		limiter.tokens--
+		return true, 0
+	}
+	if limiter.rate <= 0 {
+		return false, time.Second
+	}
+	return false, time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+}
+
+func (limiter *RateLimiter) fill(now time.Time) {
+	elapsed := now.Sub(limiter.lastFill).Seconds()
+	limiter.lastFill = now
+	limiter.tokens = math.Min(limiter.burst, limiter.tokens+elapsed*limiter.rate)
+}
+
+// RateLimitHandler wraps http.Handler and responds 429 when limiter has no
+// token left
+func RateLimitHandler(limiter *RateLimiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		handlerFunc := func(w http.ResponseWriter, r *http.Request) {
+			if allowed, wait := limiter.Allow(); !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeJSONMessage(w, http.StatusTooManyRequests, "Too many requests.")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(handlerFunc)
+	}
+}
@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	datastore "github.com/dhyaniarun1993/foody-common/datastore/mongo"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/random"
+)
+
+// MongoIndexes are the indexes of the outbox collection, see
+// Database.SyncIndexes
+var MongoIndexes = []datastore.IndexSpec{
+	{Keys: bson.D{{Key: "sentAt", Value: 1}, {Key: "createdAt", Value: 1}}},
+	{Keys: bson.D{{Key: "claimToken", Value: 1}}},
+}
+
+// mongoOrder sorts the messages oldest first
+var mongoOrder = bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}
+
+// MongoStore is an outbox in a Mongo collection
+type MongoStore struct {
+	collection *datastore.Collection
+	tracer     opentracing.Tracer
+}
+
+type mongoMessage struct {
+	ID        string            `bson:"_id"`
+	Topic     string            `bson:"topic"`
+	Key       string            `bson:"key"`
+	Payload   []byte            `bson:"payload"`
+	Headers   map[string]string `bson:"headers"`
+	CreatedAt time.Time         `bson:"createdAt"`
+	SentAt    *time.Time        `bson:"sentAt"`
+}
+
+// NewMongoStore creates a MongoStore
+func NewMongoStore(collection *datastore.Collection, tracer opentracing.Tracer) *MongoStore {
+	return &MongoStore{collection, tracer}
+}
+
+// Add writes message to the outbox. ctx must be the mongo.SessionContext of
+// the transaction of the business change, e.g. inside WithTransaction, so
+// that the message is only published if the transaction commits.
+func (store *MongoStore) Add(ctx context.Context, message Message) errors.AppError {
+	message = prepare(ctx, store.tracer, message)
+	_, err := store.collection.InsertOne(ctx, mongoMessage{
+		ID:        message.ID,
+		Topic:     message.Topic,
+		Key:       message.Key,
+		Payload:   message.Payload,
+		Headers:   message.Headers,
+		CreatedAt: message.CreatedAt,
+	})
+	if err != nil {
+		return errors.NewAppError("Unable to add message to outbox", http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+// Pending claims the oldest messages that are not sent yet, see Store
+func (store *MongoStore) Pending(ctx context.Context, limit int, lease time.Duration) ([]Message, errors.AppError) {
+	token := random.ID()
+	now := time.Now().UTC()
+	available := bson.A{bson.M{"claimedUntil": nil}, bson.M{"claimedUntil": bson.M{"$lt": now}}}
+	candidates, err := store.find(ctx, bson.M{"sentAt": nil, "$or": available},
+		options.Find().SetSort(mongoOrder).SetLimit(int64(limit)).SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.ID
+	}
+	// a candidate claimed by another relay in the meantime is not updated
+	_, updateErr := store.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "sentAt": nil, "$or": available},
+		bson.M{"$set": bson.M{"claimToken": token, "claimedUntil": now.Add(lease)}})
+	if updateErr != nil {
+		return nil, errors.NewAppError("Unable to claim outbox messages", http.StatusInternalServerError, updateErr)
+	}
+
+	messages, err := store.find(ctx, bson.M{"claimToken": token, "sentAt": nil}, options.Find().SetSort(mongoOrder))
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	keys := map[string]bool{}
+	var keyList []string
+	for _, message := range messages {
+		if !keys[message.Key] {
+			keys[message.Key] = true
+			keyList = append(keyList, message.Key)
+		}
+	}
+	pending, err := store.find(ctx, bson.M{
+		"sentAt":     nil,
+		"claimToken": bson.M{"$ne": token},
+		"createdAt":  bson.M{"$lte": messages[len(messages)-1].CreatedAt},
+		"key":        bson.M{"$in": keyList},
+	}, options.Find().SetProjection(bson.M{"_id": 1, "key": 1, "createdAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	messages, dropped := unblocked(messages, pending)
+	if len(dropped) > 0 {
+		_, updateErr = store.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": dropped}, "claimToken": token},
+			bson.M{"$set": bson.M{"claimToken": nil, "claimedUntil": nil}})
+		if updateErr != nil {
+			return nil, errors.NewAppError("Unable to release outbox messages", http.StatusInternalServerError, updateErr)
+		}
+	}
+	return messages, nil
+}
+
+// find returns the messages matching filter
+func (store *MongoStore) find(ctx context.Context, filter interface{}, opts *options.FindOptions) ([]Message, errors.AppError) {
+	cursor, err := store.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.NewAppError("Unable to read outbox", http.StatusInternalServerError, err)
+	}
+	var documents []mongoMessage
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, errors.NewAppError("Unable to read outbox", http.StatusInternalServerError, err)
+	}
+	messages := make([]Message, len(documents))
+	for i, document := range documents {
+		messages[i] = Message{
+			ID:        document.ID,
+			Topic:     document.Topic,
+			Key:       document.Key,
+			Payload:   document.Payload,
+			Headers:   document.Headers,
+			CreatedAt: document.CreatedAt,
+		}
+	}
+	return messages, nil
+}
+
+// MarkSent sets sentAt of the messages with ids
+func (store *MongoStore) MarkSent(ctx context.Context, ids []string) errors.AppError {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := store.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{"$set": bson.M{"sentAt": time.Now().UTC()}})
+	if err != nil {
+		return errors.NewAppError("Unable to mark outbox messages as sent", http.StatusInternalServerError, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/random"
+)
+
+// Message is an event written to the outbox together with the business
+// change and published later by the Relay
+type Message struct {
+	ID        string
+	Topic     string
+	Key       string
+	Payload   []byte
+	Headers   map[string]string
+	CreatedAt time.Time
+}
+
+// NewMessage creates a Message with payload encoded as JSON. Messages with
+// the same key are published in order.
+func NewMessage(topic string, key string, payload interface{}) (Message, errors.AppError) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, errors.NewAppError("Unable to encode outbox payload", http.StatusInternalServerError, err)
+	}
+	return Message{Topic: topic, Key: key, Payload: data}, nil
+}
+
+// Store reads the messages that are waiting in the outbox
+type Store interface {
+	// Pending claims up to limit messages that are not sent yet, oldest
+	// first, and hides them from the other relays for lease. Messages that
+	// come after an unsent message with the same key claimed elsewhere are
+	// left out, so that every key is published in order.
+	Pending(ctx context.Context, limit int, lease time.Duration) ([]Message, errors.AppError)
+	// MarkSent marks the messages with ids as sent
+	MarkSent(ctx context.Context, ids []string) errors.AppError
+}
+
+// Publisher publishes a message to the broker
+type Publisher interface {
+	Publish(ctx context.Context, message Message) errors.AppError
+}
+
+// MemoryPublisher keeps the published messages in memory, it is meant for
+// tests
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryPublisher creates a MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish stores message
+func (publisher *MemoryPublisher) Publish(ctx context.Context, message Message) errors.AppError {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	publisher.messages = append(publisher.messages, message)
+	return nil
+}
+
+// Messages returns the published messages in publish order
+func (publisher *MemoryPublisher) Messages() []Message {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	return append([]Message{}, publisher.messages...)
+}
+
+// prepare sets the ID, the creation time and the trace headers of message
+func prepare(ctx context.Context, tracer opentracing.Tracer, message Message) Message {
+	if message.ID == "" {
+		message.ID = random.ID()
+	}
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now().UTC()
+	}
+	headers := map[string]string{}
+	for name, value := range message.Headers {
+		headers[name] = value
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		tracer.Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(headers))
+	}
+	message.Headers = headers
+	return message
+}
+
+// unblocked drops the messages that come after an unsent message with the
+// same key that is not in messages, among pending. It returns the kept
+// messages and the ids of the dropped ones.
+func unblocked(messages []Message, pending []Message) ([]Message, []string) {
+	first := map[string]Message{}
+	for _, message := range pending {
+		if current, ok := first[message.Key]; !ok || before(message, current) {
+			first[message.Key] = message
+		}
+	}
+	var kept []Message
+	var dropped []string
+	for _, message := range messages {
+		if blocker, ok := first[message.Key]; ok && before(blocker, message) {
+			dropped = append(dropped, message.ID)
+			continue
+		}
+		kept = append(kept, message)
+	}
+	return kept, dropped
+}
+
+// before reports whether a is older than b in outbox order
+func before(a Message, b Message) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/dhyaniarun1993/foody-common/async"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/logger"
+)
+
+// RelayOption configures the optional behaviour of a Relay
+type RelayOption func(*Relay)
+
+// WithInterval sets how often the outbox is polled, 1s by default
+func WithInterval(interval time.Duration) RelayOption {
+	return func(relay *Relay) {
+		relay.interval = interval
+	}
+}
+
+// WithBatchSize sets how many messages are read at once, 100 by default
+func WithBatchSize(batchSize int) RelayOption {
+	return func(relay *Relay) {
+		relay.batchSize = batchSize
+	}
+}
+
+// WithLease sets how long the messages read by a relay are hidden from the
+// other relays, 1m by default. It must be longer than a batch takes to
+// publish.
+func WithLease(lease time.Duration) RelayOption {
+	return func(relay *Relay) {
+		relay.lease = lease
+	}
+}
+
+// WithLogger logs the failed publishes
+func WithLogger(logger *logger.Logger) RelayOption {
+	return func(relay *Relay) {
+		relay.logger = logger
+	}
+}
+
+// Relay publishes the pending messages of a Store and marks them as sent.
+// Several relays can run on the same outbox, each batch is claimed for the
+// lease. Messages are delivered at least once: a message is published again
+// if its lease ends before it is marked, e.g. when the relay stops between
+// publishing and marking it or fails to publish it.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	tracer    opentracing.Tracer
+	logger    *logger.Logger
+	interval  time.Duration
+	batchSize int
+	lease     time.Duration
+	wake      chan struct{}
+}
+
+// NewRelay creates a Relay
+func NewRelay(store Store, publisher Publisher, tracer opentracing.Tracer, opts ...RelayOption) *Relay {
+	relay := &Relay{
+		store:     store,
+		publisher: publisher,
+		tracer:    tracer,
+		interval:  time.Second,
+		batchSize: 100,
+		lease:     time.Minute,
+		wake:      make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(relay)
+	}
+	return relay
+}
+
+// Notify wakes the relay up before the next poll, e.g. after a commit or from
+// a change stream on the outbox collection
+func (relay *Relay) Notify() {
+	select {
+	case relay.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays the outbox until ctx is cancelled
+func (relay *Relay) Run(ctx context.Context) errors.AppError {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := relay.Flush(ctx); err != nil && ctx.Err() == nil && relay.logger != nil {
+			relay.logger.WithContext(ctx).WithError(err).Error("Unable to relay outbox messages")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-relay.wake:
+		}
+	}
+}
+
+// Flush publishes the pending messages until the outbox is empty and returns
+// the number of published messages. Messages with the same key are published
+// in order, messages with different keys concurrently.
+func (relay *Relay) Flush(ctx context.Context) (int, errors.AppError) {
+	published := 0
+	for {
+		messages, err := relay.store.Pending(ctx, relay.batchSize, relay.lease)
+		if err != nil {
+			return published, err
+		}
+		sent, err := relay.publish(ctx, messages)
+		if markErr := relay.store.MarkSent(ctx, sent); markErr != nil {
+			return published, markErr
+		}
+		published += len(sent)
+		if err != nil || len(messages) < relay.batchSize {
+			return published, err
+		}
+	}
+}
+
+// publish publishes messages grouped by key and returns the ids of the
+// published messages. The first failure stops the batch.
+func (relay *Relay) publish(ctx context.Context, messages []Message) ([]string, errors.AppError) {
+	var keys []string
+	byKey := map[string][]Message{}
+	for _, message := range messages {
+		if _, ok := byKey[message.Key]; !ok {
+			keys = append(keys, message.Key)
+		}
+		byKey[message.Key] = append(byKey[message.Key], message)
+	}
+
+	var mu sync.Mutex
+	var sent []string
+	group, groupCtx := async.WithContext(ctx)
+	for _, key := range keys {
+		keyMessages := byKey[key]
+		group.Go(func() errors.AppError {
+			for _, message := range keyMessages {
+				if err := relay.publishOne(groupCtx, message); err != nil {
+					return err
+				}
+				mu.Lock()
+				sent = append(sent, message.ID)
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	err := group.Wait()
+	return sent, err
+}
+
+// publishOne publishes message in a span that follows from the trace of the
+// business change
+func (relay *Relay) publishOne(ctx context.Context, message Message) errors.AppError {
+	var spanOpts []opentracing.StartSpanOption
+	if origin, err := relay.tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier(message.Headers)); err == nil {
+		spanOpts = append(spanOpts, opentracing.FollowsFrom(origin))
+	}
+	span := relay.tracer.StartSpan("Outbox.Publish", spanOpts...)
+	defer span.Finish()
+	ext.SpanKind.Set(span, ext.SpanKindProducerEnum)
+	ext.MessageBusDestination.Set(span, message.Topic)
+	span.SetTag("message.id", message.ID)
+
+	err := relay.publisher.Publish(opentracing.ContextWithSpan(ctx, span), message)
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(
+			log.String("event", "error"),
+			log.Int("status", err.StatusCode()),
+			log.String("message", err.Error()),
+		)
+	}
+	return err
+}
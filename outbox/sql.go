@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	datastore "github.com/dhyaniarun1993/foody-common/datastore/sql"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/random"
+)
+
+// MySQLSchema creates the outbox table, add it to the migrations of the
+// service
+const MySQLSchema = `CREATE TABLE outbox (
+	id CHAR(32) NOT NULL PRIMARY KEY,
+	topic VARCHAR(255) NOT NULL,
+	message_key VARCHAR(255) NOT NULL,
+	payload BLOB NOT NULL,
+	headers TEXT NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	sent_at DATETIME(6) NULL,
+	claim_token CHAR(32) NULL,
+	claimed_until DATETIME(6) NULL,
+	INDEX outbox_pending (sent_at, created_at),
+	INDEX outbox_claim (claim_token)
+)`
+
+// SQLStore is an outbox in the outbox table of a SQL database
+type SQLStore struct {
+	db     *datastore.DB
+	tracer opentracing.Tracer
+}
+
+type sqlMessage struct {
+	ID        string    `db:"id"`
+	Topic     string    `db:"topic"`
+	Key       string    `db:"message_key"`
+	Payload   []byte    `db:"payload"`
+	Headers   string    `db:"headers"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// NewSQLStore creates a SQLStore, the DSN of db must set parseTime=true
+func NewSQLStore(db *datastore.DB, tracer opentracing.Tracer) *SQLStore {
+	return &SQLStore{db, tracer}
+}
+
+// Add writes message to the outbox in tx, so that it is only published if tx
+// commits. The trace context of ctx is saved with the message.
+func (store *SQLStore) Add(ctx context.Context, tx *datastore.Tx, message Message) errors.AppError {
+	message = prepare(ctx, store.tracer, message)
+	headers, err := json.Marshal(message.Headers)
+	if err != nil {
+		return errors.NewAppError("Unable to encode outbox headers", http.StatusInternalServerError, err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO outbox (id, topic, message_key, payload, headers, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		message.ID, message.Topic, message.Key, message.Payload, string(headers), message.CreatedAt)
+	if err != nil {
+		return errors.NewAppError("Unable to add message to outbox", http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+// Pending claims the oldest messages that are not sent yet, see Store. The
+// claimed messages are read from the primary.
+func (store *SQLStore) Pending(ctx context.Context, limit int, lease time.Duration) ([]Message, errors.AppError) {
+	token := random.ID()
+	now := time.Now().UTC()
+	_, err := store.db.ExecContext(ctx,
+		"UPDATE outbox SET claim_token = ?, claimed_until = ? "+
+			"WHERE sent_at IS NULL AND (claimed_until IS NULL OR claimed_until < ?) ORDER BY created_at, id LIMIT ?",
+		token, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, errors.NewAppError("Unable to claim outbox messages", http.StatusInternalServerError, err)
+	}
+
+	var rows []sqlMessage
+	err = store.db.SelectContext(datastore.WithPrimary(ctx), &rows,
+		"SELECT id, topic, message_key, payload, headers, created_at FROM outbox "+
+			"WHERE claim_token = ? AND sent_at IS NULL ORDER BY created_at, id", token)
+	if err != nil {
+		return nil, errors.NewAppError("Unable to read outbox", http.StatusInternalServerError, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	messages := make([]Message, len(rows))
+	for i, row := range rows {
+		messages[i] = Message{
+			ID:        row.ID,
+			Topic:     row.Topic,
+			Key:       row.Key,
+			Payload:   row.Payload,
+			CreatedAt: row.CreatedAt,
+		}
+		if err := json.Unmarshal([]byte(row.Headers), &messages[i].Headers); err != nil {
+			return nil, errors.NewAppError("Unable to decode outbox headers", http.StatusInternalServerError, err)
+		}
+	}
+
+	messages, dropped, appErr := store.unblocked(ctx, token, messages)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(dropped) > 0 {
+		args := append([]interface{}{token}, stringArgs(dropped)...)
+		_, err = store.db.ExecContext(ctx, "UPDATE outbox SET claim_token = NULL, claimed_until = NULL "+
+			"WHERE claim_token = ? AND id IN ("+placeholders(len(dropped))+")", args...)
+		if err != nil {
+			return nil, errors.NewAppError("Unable to release outbox messages", http.StatusInternalServerError, err)
+		}
+	}
+	return messages, nil
+}
+
+// unblocked leaves out the claimed messages that wait for an older message
+// with the same key claimed elsewhere
+func (store *SQLStore) unblocked(ctx context.Context, token string, messages []Message) ([]Message, []string, errors.AppError) {
+	keys := map[string]bool{}
+	args := []interface{}{token, messages[len(messages)-1].CreatedAt}
+	for _, message := range messages {
+		if !keys[message.Key] {
+			keys[message.Key] = true
+			args = append(args, message.Key)
+		}
+	}
+	var rows []sqlMessage
+	err := store.db.SelectContext(datastore.WithPrimary(ctx), &rows,
+		"SELECT id, message_key, created_at FROM outbox WHERE sent_at IS NULL "+
+			"AND (claim_token IS NULL OR claim_token <> ?) AND created_at <= ? "+
+			"AND message_key IN ("+placeholders(len(keys))+")", args...)
+	if err != nil {
+		return nil, nil, errors.NewAppError("Unable to read outbox", http.StatusInternalServerError, err)
+	}
+	pending := make([]Message, len(rows))
+	for i, row := range rows {
+		pending[i] = Message{ID: row.ID, Key: row.Key, CreatedAt: row.CreatedAt}
+	}
+	kept, dropped := unblocked(messages, pending)
+	return kept, dropped, nil
+}
+
+// MarkSent sets sent_at of the messages with ids
+func (store *SQLStore) MarkSent(ctx context.Context, ids []string) errors.AppError {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := append([]interface{}{time.Now().UTC()}, stringArgs(ids)...)
+	_, err := store.db.ExecContext(ctx, "UPDATE outbox SET sent_at = ? WHERE id IN ("+placeholders(len(ids))+")", args...)
+	if err != nil {
+		return errors.NewAppError("Unable to mark outbox messages as sent", http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+// placeholders returns n comma separated placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// stringArgs converts values to query arguments
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
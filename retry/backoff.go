@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff provides the wait duration before the next attempt
+type Backoff interface {
+	// Next returns the duration to wait after the given attempt(starting at 1)
+	// has failed. previous is the duration returned for the last attempt.
+	Next(attempt int, previous time.Duration) time.Duration
+}
+
+type constantBackoff struct {
+	interval time.Duration
+}
+
+// ConstantBackoff waits the same interval between every attempt
+func ConstantBackoff(interval time.Duration) Backoff {
+	return &constantBackoff{interval}
+}
+
+func (backoff *constantBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	return backoff.interval
+}
+
+type exponentialBackoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// ExponentialBackoff doubles the wait interval after every attempt starting
+// from base and capped at max
+func ExponentialBackoff(base time.Duration, max time.Duration) Backoff {
+	return &exponentialBackoff{base, max}
+}
+
+func (backoff *exponentialBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	wait := backoff.base
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= backoff.max || wait <= 0 {
+			return backoff.max
+		}
+	}
+	if wait > backoff.max {
+		return backoff.max
+	}
+	return wait
+}
+
+type decorrelatedJitterBackoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// DecorrelatedJitterBackoff picks a random wait between base and three times
+// the previous wait, capped at max. It spreads out retries of concurrent callers.
+func DecorrelatedJitterBackoff(base time.Duration, max time.Duration) Backoff {
+	return &decorrelatedJitterBackoff{base, max}
+}
+
+func (backoff *decorrelatedJitterBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	if previous < backoff.base {
+		previous = backoff.base
+	}
+	upper := previous * 3
+	if upper <= backoff.base {
+		return backoff.base
+	}
+	wait := backoff.base + time.Duration(rand.Int63n(int64(upper-backoff.base)))
+	if wait > backoff.max {
+		return backoff.max
+	}
+	return wait
+}
@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+
+	"github.com/dhyaniarun1993/foody-common/errors"
+)
+
+// Classifier decides whether a failed attempt should be retried
+type Classifier func(err errors.AppError) bool
+
+// Policy provides the options that control how an operation is retried.
+// Zero MaxAttempts or MaxElapsedTime means no limit on that dimension.
+type Policy struct {
+	Backoff        Backoff
+	MaxAttempts    int
+	MaxElapsedTime time.Duration
+	Classifier     Classifier
+}
+
+// DefaultClassifier retries errors marked as retryable and errors with a
+// status code that signals a temporary failure
+func DefaultClassifier(err errors.AppError) bool {
+	if err.Retryable() {
+		return true
+	}
+	switch err.StatusCode() {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Do calls operation until it succeeds, the classifier rejects the error, the
+// policy limits are reached or ctx is cancelled. The error of the last attempt
+// is returned. Every attempt is logged on the span in ctx, if any.
+func Do(ctx context.Context, policy Policy, operation func() errors.AppError) errors.AppError {
+	classifier := policy.Classifier
+	if classifier == nil {
+		classifier = DefaultClassifier
+	}
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = ConstantBackoff(0)
+	}
+
+	span := opentracing.SpanFromContext(ctx)
+	start := time.Now()
+	var wait time.Duration
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil {
+			logAttempt(span, attempt, nil, 0)
+			return nil
+		}
+
+		if !classifier(err) || (policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts) {
+			logAttempt(span, attempt, err, 0)
+			return err
+		}
+
+		wait = backoff.Next(attempt, wait)
+		if policy.MaxElapsedTime > 0 && time.Since(start)+wait > policy.MaxElapsedTime {
+			logAttempt(span, attempt, err, 0)
+			return err
+		}
+		logAttempt(span, attempt, err, wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.NewAppError("Retry cancelled: "+ctx.Err().Error(), err.StatusCode(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func logAttempt(span opentracing.Span, attempt int, err errors.AppError, wait time.Duration) {
+	if span == nil {
+		return
+	}
+	if err == nil {
+		span.LogFields(
+			log.String("event", "retry.attempt"),
+			log.Int("attempt", attempt),
+			log.Bool("success", true),
+		)
+		return
+	}
+	span.LogFields(
+		log.String("event", "retry.attempt"),
+		log.Int("attempt", attempt),
+		log.Bool("success", false),
+		log.String("error", err.Error()),
+		log.Int("status", err.StatusCode()),
+		log.String("backoff", wait.String()),
+	)
+}
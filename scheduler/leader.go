@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/dhyaniarun1993/foody-common/internal/random"
+)
+
+// Leader elects the replica that runs the jobs
+type Leader interface {
+	// Acquire makes this replica the leader for ttl, or extends its lease if
+	// it is the leader already. It returns false when another replica leads.
+	Acquire(ctx context.Context, ttl time.Duration) (bool, error)
+	// Release gives up the lead so another replica can take it right away
+	Release(ctx context.Context) error
+}
+
+// acquireScript extends the lease of the leader or takes the lead when there
+// is no leader
+var acquireScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lock only if this replica holds it
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisLeader is a Leader holding a Redis lock, the replica that sets key
+// leads until it stops extending its lease
+type RedisLeader struct {
+	client *redis.Client
+	key    string
+	token  string
+}
+
+// NewRedisLeader creates a RedisLeader on key
+func NewRedisLeader(client *redis.Client, key string) *RedisLeader {
+	return &RedisLeader{client, key, random.ID()}
+}
+
+// Acquire takes or extends the lock for ttl
+func (leader *RedisLeader) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	acquired, err := acquireScript.Run(leader.client.WithContext(ctx), []string{leader.key},
+		leader.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+// Release deletes the lock if it is held by this replica
+func (leader *RedisLeader) Release(ctx context.Context) error {
+	return releaseScript.Run(leader.client.WithContext(ctx), []string{leader.key}, leader.token).Err()
+}
@@ -0,0 +1,298 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/async"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/internal/timeutil"
+	"github.com/dhyaniarun1993/foody-common/logger"
+)
+
+// Task is the work of a scheduled job
+type Task func(ctx context.Context) errors.AppError
+
+// Options configures a Scheduler, zero values use the defaults
+type Options struct {
+	// Leader elects the replica that runs the jobs, every replica runs them
+	// when it is nil
+	Leader Leader
+	// LeaseTTL is how long the leader keeps the lead without extending it,
+	// 15s by default. The lease is extended every third of it.
+	LeaseTTL time.Duration
+	// LeaderTimeout bounds every call to the Leader, LeaseTTL/6 by default
+	LeaderTimeout time.Duration
+
+	// Logger logs the failed runs and the leadership changes when set
+	Logger *logger.Logger
+}
+
+// JobOption configures a job
+type JobOption func(*job)
+
+// WithJitter delays every run by a random duration up to max
+func WithJitter(max time.Duration) JobOption {
+	return func(job *job) {
+		job.jitter = max
+	}
+}
+
+// WithTimeout cancels the context of a run after timeout
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(job *job) {
+		job.timeout = timeout
+	}
+}
+
+// AllowOverlap starts a run even if the previous one is still running, runs
+// are skipped by default
+func AllowOverlap() JobOption {
+	return func(job *job) {
+		job.allowOverlap = true
+	}
+}
+
+type job struct {
+	name         string
+	schedule     Schedule
+	task         Task
+	jitter       time.Duration
+	timeout      time.Duration
+	allowOverlap bool
+	running      int32
+}
+
+// Scheduler runs jobs periodically. With a Leader only the elected replica
+// runs them, and the runs in progress are cancelled when it loses the lead.
+type Scheduler struct {
+	tracer  opentracing.Tracer
+	options Options
+	jobs    []*job
+
+	mu        sync.Mutex
+	leaderCtx context.Context
+	stepDown  context.CancelFunc
+}
+
+// New creates a Scheduler
+func New(tracer opentracing.Tracer, options Options) *Scheduler {
+	if options.LeaseTTL <= 0 {
+		options.LeaseTTL = 15 * time.Second
+	}
+	if options.LeaderTimeout <= 0 {
+		options.LeaderTimeout = options.LeaseTTL / 6
+	}
+	return &Scheduler{tracer: tracer, options: options}
+}
+
+// Add schedules task as job name, it must be called before Run
+func (scheduler *Scheduler) Add(name string, schedule Schedule, task Task, opts ...JobOption) {
+	job := &job{name: name, schedule: schedule, task: task}
+	for _, opt := range opts {
+		opt(job)
+	}
+	scheduler.jobs = append(scheduler.jobs, job)
+}
+
+// Cron schedules task as job name at the times of the cron expression, in
+// the local time zone
+func (scheduler *Scheduler) Cron(name string, expression string, task Task, opts ...JobOption) error {
+	schedule, err := ParseCron(expression)
+	if err != nil {
+		return err
+	}
+	scheduler.Add(name, schedule, task, opts...)
+	return nil
+}
+
+// Run runs the jobs until ctx is cancelled, it waits for the runs in progress
+// and gives up the lead before returning
+func (scheduler *Scheduler) Run(ctx context.Context) errors.AppError {
+	if len(scheduler.jobs) == 0 {
+		return errors.NewAppError("No scheduled job added", http.StatusInternalServerError, nil)
+	}
+
+	group, _ := async.WithContext(ctx)
+	if scheduler.options.Leader == nil {
+		scheduler.lead(ctx)
+	} else {
+		group.Go(func() errors.AppError {
+			scheduler.elect(ctx)
+			return nil
+		})
+	}
+	for _, job := range scheduler.jobs {
+		job := job
+		group.Go(func() errors.AppError {
+			scheduler.loop(ctx, group, job)
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+// elect takes or extends the lead every third of the lease until ctx is
+// cancelled. The lead is kept through Redis failures while the lease lasts
+// longer than the next check, so this replica resigns before another one
+// can take the lead.
+func (scheduler *Scheduler) elect(ctx context.Context) {
+	interval := scheduler.options.LeaseTTL / 3
+	margin := interval + scheduler.options.LeaderTimeout
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var leaseEnd time.Time
+	for {
+		start := time.Now()
+		acquireCtx, cancel := context.WithTimeout(ctx, scheduler.options.LeaderTimeout)
+		acquired, err := scheduler.options.Leader.Acquire(acquireCtx, scheduler.options.LeaseTTL)
+		cancel()
+		switch {
+		case err != nil && ctx.Err() == nil:
+			scheduler.logError(ctx, err, "Unable to extend scheduler lead")
+			if time.Now().After(leaseEnd.Add(-margin)) {
+				scheduler.resign()
+			}
+		case acquired:
+			leaseEnd = start.Add(scheduler.options.LeaseTTL)
+			scheduler.lead(ctx)
+		default:
+			scheduler.resign()
+		}
+
+		select {
+		case <-ctx.Done():
+			scheduler.resign()
+			releaseCtx, cancel := context.WithTimeout(context.Background(), scheduler.options.LeaderTimeout)
+			scheduler.options.Leader.Release(releaseCtx)
+			cancel()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead makes this replica run the jobs
+func (scheduler *Scheduler) lead(ctx context.Context) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.leaderCtx == nil {
+		scheduler.leaderCtx, scheduler.stepDown = context.WithCancel(ctx)
+		scheduler.logInfo(ctx, "Scheduler lead acquired")
+	}
+}
+
+// resign stops this replica from running the jobs and cancels the runs in
+// progress
+func (scheduler *Scheduler) resign() {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.leaderCtx != nil {
+		scheduler.stepDown()
+		scheduler.logInfo(scheduler.leaderCtx, "Scheduler lead lost")
+		scheduler.leaderCtx, scheduler.stepDown = nil, nil
+	}
+}
+
+// leading returns the context of the current lead, nil if this replica does
+// not lead
+func (scheduler *Scheduler) leading() context.Context {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	return scheduler.leaderCtx
+}
+
+// loop starts the runs of job at its scheduled times until ctx is cancelled
+func (scheduler *Scheduler) loop(ctx context.Context, group *async.Async, job *job) {
+	next := time.Now()
+	for {
+		next = job.schedule.Next(next)
+		if next.IsZero() {
+			scheduler.logError(ctx, nil, "Scheduled job never runs", zap.String("job", job.name))
+			return
+		}
+		wait := time.Until(next)
+		if job.jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(job.jitter)))
+		}
+		timeutil.Sleep(ctx, wait)
+		if ctx.Err() != nil {
+			return
+		}
+
+		runCtx := scheduler.leading()
+		if runCtx == nil {
+			continue
+		}
+		if job.allowOverlap {
+			atomic.AddInt32(&job.running, 1)
+		} else if !atomic.CompareAndSwapInt32(&job.running, 0, 1) {
+			scheduler.logInfo(ctx, "Scheduled job still running, run skipped", zap.String("job", job.name))
+			continue
+		}
+		scheduled := next
+		group.Go(func() errors.AppError {
+			defer atomic.AddInt32(&job.running, -1)
+			scheduler.run(runCtx, job, scheduled)
+			return nil
+		})
+	}
+}
+
+// run runs job in its own root span and logs the failure
+func (scheduler *Scheduler) run(ctx context.Context, job *job, scheduled time.Time) {
+	span := scheduler.tracer.StartSpan("Scheduler." + job.name)
+	defer span.Finish()
+	ext.Component.Set(span, "scheduler")
+	span.SetTag("scheduler.job", job.name)
+	span.SetTag("scheduler.scheduled", scheduled.Format(time.RFC3339))
+	if job.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.timeout)
+		defer cancel()
+	}
+	spanCtx := opentracing.ContextWithSpan(ctx, span)
+
+	if err := call(spanCtx, job); err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(
+			log.String("event", "error"),
+			log.Int("status", err.StatusCode()),
+			log.String("message", err.Error()),
+		)
+		scheduler.logError(spanCtx, err, "Scheduled job failed", zap.String("job", job.name))
+	}
+}
+
+// call runs the task of job and turns a panic into an error
+func call(ctx context.Context, job *job) (err errors.AppError) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.NewAppError(fmt.Sprintf("Scheduled job %s panicked: %v", job.name, r),
+				http.StatusInternalServerError, nil)
+		}
+	}()
+	return job.task(ctx)
+}
+
+func (scheduler *Scheduler) logInfo(ctx context.Context, message string, fields ...zap.Field) {
+	if scheduler.options.Logger != nil {
+		scheduler.options.Logger.WithContext(ctx).Info(message, fields...)
+	}
+}
+
+func (scheduler *Scheduler) logError(ctx context.Context, err error, message string, fields ...zap.Field) {
+	if scheduler.options.Logger != nil {
+		scheduler.options.Logger.WithContext(ctx).WithError(err).Error(message, fields...)
+	}
+}
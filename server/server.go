@@ -0,0 +1,318 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+
+	"github.com/dhyaniarun1993/foody-common/authentication"
+	"github.com/dhyaniarun1993/foody-common/errors"
+	"github.com/dhyaniarun1993/foody-common/health"
+	"github.com/dhyaniarun1993/foody-common/logger"
+	"github.com/dhyaniarun1993/foody-common/middlewares"
+	tracing "github.com/dhyaniarun1993/foody-common/tracer"
+)
+
+// probe paths
+const (
+	LivenessPath  = "/live"
+	ReadinessPath = "/ready"
+)
+
+// Configuration provides configuration for the HTTP server
+type Configuration struct {
+	Port int `required:"true"`
+
+	ReadTimeout  string `split_words:"true" default:"5s"`
+	WriteTimeout string `split_words:"true" default:"10s"`
+	IdleTimeout  string `split_words:"true" default:"60s"`
+	// RequestTimeout is the timeout of the TimeoutHandler, it should be
+	// shorter than WriteTimeout
+	RequestTimeout string `split_words:"true" default:"5s"`
+
+	// ShutdownDelay is how long the server keeps serving after it is not ready
+	// anymore, so load balancers stop sending traffic before it stops
+	// accepting connections
+	ShutdownDelay string `split_words:"true" default:"5s"`
+	// ShutdownTimeout is how long in flight requests are drained
+	ShutdownTimeout string `split_words:"true" default:"30s"`
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithLogger logs the lifecycle of the server and the resources that fail
+// to close
+func WithLogger(logger *logger.Logger) Option {
+	return func(server *Server) {
+		server.logger = logger
+	}
+}
+
+// WithoutAuth does not require the authentication headers, for services
+// with public routes. Auth of the context is then not set.
+func WithoutAuth() Option {
+	return func(server *Server) {
+		server.auth = false
+	}
+}
+
+// WithHealth makes the readiness probe fail when a critical check of
+// registry is down
+func WithHealth(registry *health.Registry) Option {
+	return func(server *Server) {
+		server.health = registry
+	}
+}
+
+// WithMiddlewares adds middlewares after the default ones
+func WithMiddlewares(middlewares ...mux.MiddlewareFunc) Option {
+	return func(server *Server) {
+		server.middlewares = append(server.middlewares, middlewares...)
+	}
+}
+
+type resource struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Server serves a router with request tracing, authentication and request
+// timeout, plus the liveness and readiness probes. It shuts down gracefully
+// on SIGTERM or SIGINT.
+type Server struct {
+	httpServer      *http.Server
+	logger          *logger.Logger
+	health          *health.Registry
+	auth            bool
+	middlewares     []mux.MiddlewareFunc
+	shutdownDelay   time.Duration
+	shutdownTimeout time.Duration
+
+	ready     int32
+	mu        sync.Mutex
+	resources []resource
+}
+
+// New creates a Server for router
+func New(configuration Configuration, router *mux.Router, tracer opentracing.Tracer, opts ...Option) (*Server, error) {
+	readTimeout, err := parseDuration("ReadTimeout", configuration.ReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	writeTimeout, err := parseDuration("WriteTimeout", configuration.WriteTimeout)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := parseDuration("IdleTimeout", configuration.IdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	requestTimeout, err := parseDuration("RequestTimeout", configuration.RequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	shutdownDelay, err := parseDuration("ShutdownDelay", configuration.ShutdownDelay)
+	if err != nil {
+		return nil, err
+	}
+	shutdownTimeout, err := parseDuration("ShutdownTimeout", configuration.ShutdownTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		auth:            true,
+		shutdownDelay:   shutdownDelay,
+		shutdownTimeout: durationOr(shutdownTimeout, 30*time.Second),
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	chain := []mux.MiddlewareFunc{tracing.TraceRequest(tracer, nil, nil)}
+	if server.auth {
+		chain = append(chain, authentication.AuthHandler())
+	}
+	if requestTimeout > 0 {
+		chain = append(chain, middlewares.TimeoutHandler(requestTimeout))
+	}
+	chain = append(chain, server.middlewares...)
+
+	handler := http.NewServeMux()
+	handler.HandleFunc(LivenessPath, server.live)
+	handler.HandleFunc(ReadinessPath, server.readiness)
+	handler.Handle("/", middlewares.ChainHandlerMiddlewares(router, chain...))
+
+	server.httpServer = &http.Server{
+		Addr:         fmt.Sprintf(":%d", configuration.Port),
+		Handler:      handler,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		IdleTimeout:  idleTimeout,
+	}
+	return server, nil
+}
+
+// OnShutdown registers close to run after the requests are drained.
+// Resources are closed in the reverse order of registration, so register
+// them in the order they are created.
+func (server *Server) OnShutdown(name string, close func(ctx context.Context) error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.resources = append(server.resources, resource{name, close})
+}
+
+// AddCloser registers closer to close after the requests are drained, e.g.
+// the closer returned by InitJaeger
+func (server *Server) AddCloser(name string, closer io.Closer) {
+	server.OnShutdown(name, func(ctx context.Context) error {
+		return closer.Close()
+	})
+}
+
+// Handler returns the handler of the server
+func (server *Server) Handler() http.Handler {
+	return server.httpServer.Handler
+}
+
+// Ready reports whether the server accepts traffic
+func (server *Server) Ready() bool {
+	return atomic.LoadInt32(&server.ready) == 1
+}
+
+// Run serves until ctx is cancelled or SIGTERM or SIGINT is received, then
+// it shuts down: the readiness probe fails, the server keeps serving for
+// ShutdownDelay, stops accepting connections, drains in flight requests
+// within ShutdownTimeout and closes the resources.
+func (server *Server) Run(ctx context.Context) errors.AppError {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", server.httpServer.Addr)
+	if err != nil {
+		appErr := errors.NewAppError("Unable to listen", http.StatusInternalServerError, err)
+		server.logError(appErr, "Unable to listen")
+		server.close()
+		return appErr
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.httpServer.Serve(listener)
+	}()
+	atomic.StoreInt32(&server.ready, 1)
+	server.logInfo("Server started", zap.String("address", listener.Addr().String()))
+
+	var appErr errors.AppError
+	select {
+	case err := <-serveErr:
+		atomic.StoreInt32(&server.ready, 0)
+		appErr = errors.NewAppError("Server stopped", http.StatusInternalServerError, err)
+		server.logError(appErr, "Server stopped")
+		server.close()
+		return appErr
+	case <-ctx.Done():
+	}
+
+	atomic.StoreInt32(&server.ready, 0)
+	server.logInfo("Server shutting down", zap.Duration("delay", server.shutdownDelay))
+	time.Sleep(server.shutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+	defer cancel()
+	if err := server.httpServer.Shutdown(shutdownCtx); err != nil {
+		appErr = errors.NewAppError("Unable to drain requests", http.StatusInternalServerError, err)
+		server.logError(appErr, "Unable to drain requests")
+	}
+	if err := server.close(); err != nil && appErr == nil {
+		appErr = err
+	}
+	server.logInfo("Server stopped")
+	return appErr
+}
+
+// close closes the resources in reverse order and returns the first error
+func (server *Server) close() errors.AppError {
+	server.mu.Lock()
+	resources := server.resources
+	server.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+	defer cancel()
+	var appErr errors.AppError
+	for i := len(resources) - 1; i >= 0; i-- {
+		if err := resources[i].close(ctx); err != nil {
+			err := errors.NewAppError("Unable to close "+resources[i].name, http.StatusInternalServerError, err)
+			server.logError(err, "Unable to close resource", zap.String("resource", resources[i].name))
+			if appErr == nil {
+				appErr = err
+			}
+		}
+	}
+	return appErr
+}
+
+func (server *Server) live(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, "live")
+}
+
+func (server *Server) readiness(w http.ResponseWriter, r *http.Request) {
+	if !server.Ready() {
+		writeStatus(w, http.StatusServiceUnavailable, "not ready")
+		return
+	}
+	if server.health != nil {
+		server.health.ReadyHandler().ServeHTTP(w, r)
+		return
+	}
+	writeStatus(w, http.StatusOK, "ready")
+}
+
+func writeStatus(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"status": %q}`, message)
+}
+
+func (server *Server) logInfo(message string, fields ...zap.Field) {
+	if server.logger != nil {
+		server.logger.Info(message, fields...)
+	}
+}
+
+func (server *Server) logError(err error, message string, fields ...zap.Field) {
+	if server.logger != nil {
+		server.logger.WithError(err).Error(message, fields...)
+	}
+}
+
+func parseDuration(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v: %w", name, err)
+	}
+	return duration, nil
+}
+
+func durationOr(duration time.Duration, fallback time.Duration) time.Duration {
+	if duration > 0 {
+		return duration
+	}
+	return fallback
+}